@@ -2,15 +2,28 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/redshift"
 	"github.com/aws/aws-sdk-go/service/redshift/redshiftiface"
 	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
 	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
+	"github.com/aws/aws-sdk-go/service/redshiftserverless"
+	"github.com/aws/aws-sdk-go/service/redshiftserverless/redshiftserverlessiface"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
@@ -18,299 +31,1729 @@ import (
 	awsModels "github.com/grafana/grafana-aws-sdk/pkg/sql/models"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/redshift-datasource/pkg/redshift/models"
 	"github.com/grafana/sqlds/v2"
+	"github.com/jpillora/backoff"
 )
 
+const (
+	defaultPollInterval = 100 * time.Millisecond
+	maxPollInterval     = 5 * time.Second
+	defaultPollFactor   = 1.5
+	defaultSecretTagKey = "RedshiftQueryOwner"
+)
+
+// UserAgentName is the product sent in the User-Agent of every AWS call. Builds embedding the
+// plugin can change it at init time or with -ldflags "-X <package>.UserAgentName=<name>".
+var UserAgentName = "Redshift"
+
+// API calls the Redshift APIs for a data source. It's safe for concurrent use once created, so a single
+// instance serves the concurrent queries of the panels: its settings aren't changed after New, the caches
+// and the in-flight statements are guarded by mutexes, and the AWS clients are safe for concurrent use.
+// The exported fields must not be changed while the API is in use.
 type API struct {
 	DataClient       redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
 	SecretsClient    secretsmanageriface.SecretsManagerAPI
 	ManagementClient redshiftiface.RedshiftAPI
-	settings         *models.RedshiftDataSourceSettings
+	EC2Client        ec2iface.EC2API
+	ServerlessClient redshiftserverlessiface.RedshiftServerlessAPI
+	// Logger receives the debug logs of the API, the plugin logger is used if nil
+	Logger log.Logger
+	// Metrics receives the measures of the Data API calls, they are discarded if nil
+	Metrics Metrics
+
+	settings *models.RedshiftDataSourceSettings
+	cache    *resourceCache
+	flights  flightGroup
+	secrets  *secretCache
+	inflight inflightStatements
+	canceled canceledStatements
+	// fromSecret is resolved on first use, see settingsFromSecret
+	fromSecret secretSettings
+}
+
+// RedshiftAPI is the interface of API, so its consumers can use a mock (see the mocks package) in their tests
+type RedshiftAPI interface {
+	api.AWSAPI
+	ExecuteStatement(ctx context.Context, input *StatementInput) (*api.ExecuteQueryOutput, error)
+	BatchExecute(ctx context.Context, queries []string) (*api.ExecuteQueryOutput, error)
+	ExecuteAndWait(ctx context.Context, input *StatementInput) (*Result, error)
+	StatementStatus(ctx aws.Context, output *api.ExecuteQueryOutput) (*StatementStatus, error)
+	WaitOnQuery(ctx aws.Context, output *api.ExecuteQueryOutput, pollInterval time.Duration) (*StatementStatus, error)
+	StopWithContext(ctx aws.Context, output *api.ExecuteQueryOutput) error
+	GetResult(ctx aws.Context, id string, maxRows int) (*redshiftdataapiservice.GetStatementResultOutput, error)
+	ListStatements(ctx aws.Context, filter ListStatementsFilter) ([]StatementSummary, error)
+	Schemas(ctx aws.Context, options sqlds.Options) ([]string, error)
+	Tables(ctx aws.Context, options sqlds.Options) ([]string, error)
+	Columns(ctx aws.Context, options sqlds.Options) ([]string, error)
+	ColumnsWithTypes(ctx aws.Context, options sqlds.Options) ([]Column, error)
+	Secrets(ctx aws.Context) ([]models.ManagedSecret, error)
+	Secret(ctx aws.Context, options sqlds.Options) (*models.RedshiftSecret, error)
+	Clusters() ([]models.RedshiftCluster, error)
+	HealthCheck(ctx context.Context) error
+	Settings() *models.RedshiftDataSourceSettings
+	Close() error
+}
+
+var _ RedshiftAPI = (*API)(nil)
+
+// NewRedshiftAPI is New returning the RedshiftAPI interface
+func NewRedshiftAPI(sessionCache *awsds.SessionCache, settings *models.RedshiftDataSourceSettings) (RedshiftAPI, error) {
+	return newRegionalAPI(sessionCache, settings, nil)
+}
+
+// New returns an API able to run queries. Its AWS clients use the HTTP client configured for the datasource,
+// which goes through the proxy set in HTTPS_PROXY and NO_PROXY, if any. Only the regions are validated: the
+// datasource is created before its settings are complete, the rest is validated by CheckSettings.
+func New(sessionCache *awsds.SessionCache, settings awsModels.Settings) (api.AWSAPI, error) {
+	return newRegionalAPI(sessionCache, settings.(*models.RedshiftDataSourceSettings), nil)
+}
+
+// NewLoader returns a loader like New whose AWS clients send their requests with httpClient instead,
+// e.g. to go through a specific proxy or trust a custom CA bundle.
+func NewLoader(httpClient *http.Client) api.Loader {
+	return func(sessionCache *awsds.SessionCache, settings awsModels.Settings) (api.AWSAPI, error) {
+		return newRegionalAPI(sessionCache, settings.(*models.RedshiftDataSourceSettings), httpClient)
+	}
+}
+
+func newRegionalAPI(sessionCache *awsds.SessionCache, redshiftSettings *models.RedshiftDataSourceSettings, httpClient *http.Client) (*API, error) {
+	if err := validateRegions(redshiftSettings); err != nil {
+		return nil, err
+	}
+	return newAPI(sessionCache, redshiftSettings, httpClient)
+}
+
+// NewConfigAPI returns an API without validating the regions. It's meant for the resources
+// requested by the configuration page (regions, secrets, clusters...) while the settings are still incomplete.
+func NewConfigAPI(sessionCache *awsds.SessionCache, settings awsModels.Settings) (api.AWSAPI, error) {
+	return newAPI(sessionCache, settings.(*models.RedshiftDataSourceSettings), nil)
+}
+
+// secretSettings are the settings completed with the cluster and database of the managed secret
+type secretSettings struct {
+	mu       sync.Mutex
+	settings *models.RedshiftDataSourceSettings
+}
+
+// settingsFromSecret returns the settings completed with the cluster and database of the managed secret, if missing.
+// The secret is read on first use with the context of the call rather than when creating the API, so a slow or
+// denied secret doesn't fail the creation of the datasource. The settings are returned as is if it can't be read.
+func (c *API) settingsFromSecret(ctx aws.Context) (*models.RedshiftDataSourceSettings, error) {
+	if !c.settings.UseManagedSecret || c.settings.ManagedSecret.ARN == "" {
+		return c.settings, nil
+	}
+	if (c.settings.ClusterIdentifier != "" || c.settings.WorkgroupName != "") && c.settings.Database != "" {
+		return c.settings, nil
+	}
+	c.fromSecret.mu.Lock()
+	defer c.fromSecret.mu.Unlock()
+	if c.fromSecret.settings != nil {
+		return c.fromSecret.settings, nil
+	}
+	secret, err := c.Secret(ctx, sqlds.Options{"secretARN": c.settings.ManagedSecret.ARN})
+	if err != nil {
+		return c.settings, fmt.Errorf("unable to read the cluster and database of the managed secret: %w", err)
+	}
+	res := *c.settings
+	res.ApplySecret(secret)
+	c.fromSecret.settings = &res
+	return &res, nil
+}
+
+var secretARNRegexp = regexp.MustCompile(`^arn:aws[\w-]*:secretsmanager:[a-z0-9-]+:\d{12}:secret:[\w/+=.@-]+$`)
+
+// validateSecretARN checks the ARNs of the secrets chosen at query time, the one of the settings is picked from the list
+func validateSecretARN(arn string) error {
+	if !secretARNRegexp.MatchString(arn) {
+		return fmt.Errorf("invalid secret ARN %q, expected arn:aws:secretsmanager:<region>:<account>:secret:<name>", arn)
+	}
+	return nil
+}
+
+// CheckSettings validates the settings used by the calls made with ctx, including its overrides.
+// ExecuteAndWait, HealthCheck and the queries of the driver check them before running any statement.
+func (c *API) CheckSettings(ctx context.Context) error {
+	if _, err := c.settingsFromSecret(ctx); err != nil {
+		return err
+	}
+	return validateSettings(c.overriddenSettings(ctx))
+}
+
+// checkStatementSettings is CheckSettings with the database and the secret of the statement, which replace the ones of the settings
+func (c *API) checkStatementSettings(ctx context.Context, input *StatementInput) error {
+	return validateSettings(Overrides{Database: input.Database, SecretARN: input.SecretARN}.apply(c.overriddenSettings(ctx)))
+}
+
+// validateSettings checks that the settings identify a cluster or workgroup, a database and a usable auth mode
+func validateSettings(settings *models.RedshiftDataSourceSettings) error {
+	switch {
+	case settings.ClusterIdentifier != "" && settings.WorkgroupName != "":
+		return fmt.Errorf("cluster identifier and workgroup name are mutually exclusive, only one of them can be set")
+	case settings.ClusterIdentifier == "" && settings.WorkgroupName == "":
+		return fmt.Errorf("missing cluster identifier or workgroup name")
+	case settings.Database == "":
+		return fmt.Errorf("missing database")
+	}
+	switch {
+	case settings.PollInterval < 0 || settings.MaxPollInterval < 0:
+		return fmt.Errorf("invalid poll interval, it must be positive")
+	case settings.PollFactor != 0 && settings.PollFactor < 1:
+		return fmt.Errorf("invalid poll factor %v, it must be at least 1", settings.PollFactor)
+	case settings.MaxPollInterval > 0 && settings.PollInterval > settings.MaxPollInterval:
+		return fmt.Errorf("invalid poll interval, %dms is longer than the maximum of %dms", settings.PollInterval, settings.MaxPollInterval)
+	}
+	if _, err := time.LoadLocation(settings.TimestampTimeZone); err != nil {
+		return fmt.Errorf("invalid timestamp time zone %q: %w", settings.TimestampTimeZone, err)
+	}
+	if settings.QuerySecretARN != "" {
+		if settings.DisableManagedSecrets {
+			return fmt.Errorf("%w, the secret of the query can't be used", ManagedSecretsDisabledError)
+		}
+		if err := validateSecretARN(settings.QuerySecretARN); err != nil {
+			return err
+		}
+		// The secret of the query replaces the credentials of the settings
+		return nil
+	}
+	// When using a managed secret, the database user is read from the secret, so it's not checked
+	if settings.UseManagedSecret {
+		if settings.DisableManagedSecrets {
+			return fmt.Errorf("%w, a managed secret can't be used", ManagedSecretsDisabledError)
+		}
+		if settings.ManagedSecret.ARN == "" {
+			return fmt.Errorf("missing managed secret, select one or use temporary credentials instead")
+		}
+	} else if settings.WorkgroupName != "" {
+		// Redshift Serverless maps the IAM identity of Grafana to a database user, GetClusterCredentials is only for clusters
+		if settings.DBUser != "" {
+			return fmt.Errorf("%w: a database user can't be used with the workgroup %s, use a managed secret or remove the database user to use the IAM identity", UnsupportedAuthError, settings.WorkgroupName)
+		}
+	} else if settings.DBUser == "" {
+		return fmt.Errorf("missing database user, it's required when using temporary credentials with a cluster")
+	}
+	return nil
+}
+
+// newAPI creates the AWS clients with httpClient, or with the HTTP client of the datasource if nil
+func newAPI(sessionCache *awsds.SessionCache, redshiftSettings *models.RedshiftDataSourceSettings, httpClient *http.Client) (*API, error) {
+	if httpClient == nil {
+		httpClientProvider := sdkhttpclient.NewProvider()
+		httpClientOptions, err := redshiftSettings.Config.HTTPClientOptions()
+		if err != nil {
+			backend.Logger.Error("failed to create HTTP client options", "error", err.Error())
+			return nil, err
+		}
+		httpClient, err = httpClientProvider.New(httpClientOptions)
+		if err != nil {
+			backend.Logger.Error("failed to create HTTP client", "error", err.Error())
+			return nil, err
+		}
+	}
+
+	var cache *resourceCache
+	switch {
+	case redshiftSettings.CacheTTL == 0:
+		cache = newResourceCache(defaultCacheTTL)
+	case redshiftSettings.CacheTTL > 0:
+		cache = newResourceCache(time.Duration(redshiftSettings.CacheTTL) * time.Second)
+	}
+
+	// The session assumes the configured role (assumeRoleARN and externalId) if any, refreshing
+	// its credentials when they expire, so all the clients below act in the target account
+	sess, err := sessionCache.GetSession(awsds.SessionConfig{
+		Settings:      redshiftSettings.AWSDatasourceSettings,
+		HTTPClient:    httpClient,
+		UserAgentName: aws.String(UserAgentName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions are cached regardless of their HTTP client, so it's set on every client as well
+	config := aws.NewConfig().WithHTTPClient(httpClient)
+	secretsConfig := config
+	if redshiftSettings.SecretsRegion != "" {
+		// The endpoint is resolved for the region of the client config
+		secretsConfig = config.Copy().WithRegion(redshiftSettings.SecretsRegion)
+	}
+	res := &API{
+		DataClient:       redshiftdataapiservice.New(sess, config),
+		ManagementClient: redshift.New(sess, config),
+		EC2Client:        ec2.New(sess, config),
+		ServerlessClient: redshiftserverless.New(sess, config),
+		settings:         redshiftSettings,
+		cache:            cache,
+		secrets:          newSecretCache(defaultSecretCacheTTL),
+	}
+	// Without the client, no Secrets Manager permission is needed
+	if !redshiftSettings.DisableManagedSecrets {
+		res.SecretsClient = secretsmanager.New(sess, secretsConfig)
+	}
+	return res, nil
+}
+
+type apiInput struct {
+	ClusterIdentifier *string
+	WorkgroupName     *string
+	Database          *string
+	DbUser            *string
+	SecretARN         *string
+}
+
+// apiInput returns the parameters identifying the cluster and the credentials of the Data API calls.
+// SecretARN and DbUser are mutually exclusive in a call: the managed secret takes precedence when it's
+// enabled, even if a database user is configured as well for ClusterCredentials. The secret chosen by
+// the query arguments takes precedence over both. The overrides of the context, if any, are applied.
+func (c *API) apiInput(ctx context.Context) apiInput {
+	settings := c.overriddenSettings(ctx)
+	res := apiInput{
+		Database: aws.String(settings.Database),
+	}
+	// Redshift Serverless is identified by its workgroup instead of a cluster
+	if settings.WorkgroupName != "" {
+		res.WorkgroupName = aws.String(settings.WorkgroupName)
+	} else {
+		res.ClusterIdentifier = aws.String(settings.ClusterIdentifier)
+	}
+	if settings.QuerySecretARN != "" {
+		res.SecretARN = aws.String(settings.QuerySecretARN)
+	} else if settings.UseManagedSecret {
+		res.SecretARN = aws.String(settings.ManagedSecret.ARN)
+	} else if settings.WorkgroupName == "" {
+		// The Data API gets temporary credentials for the user with GetClusterCredentials. A workgroup
+		// rejects a database user, the Data API uses the IAM identity of the caller instead.
+		res.DbUser = aws.String(settings.DBUser)
+	}
+	return res
+}
+
+// ClusterCredentials returns temporary credentials of the configured database user, whether the
+// statements use a managed secret or not. durationSeconds is the lifetime of the credentials, 0 uses the default.
+func (c *API) ClusterCredentials(ctx aws.Context, durationSeconds int64) (*redshift.GetClusterCredentialsOutput, error) {
+	settings := c.overriddenSettings(ctx)
+	if settings.WorkgroupName != "" {
+		return nil, fmt.Errorf("%w: temporary credentials of a database user are only available for clusters, not for the workgroup %s", UnsupportedAuthError, settings.WorkgroupName)
+	}
+	dbUser := settings.DBUser
+	if dbUser == "" {
+		return nil, fmt.Errorf("missing database user, it's required to get temporary credentials")
+	}
+	if settings.ClusterIdentifier == "" {
+		return nil, fmt.Errorf("missing cluster identifier, temporary credentials are only available for clusters")
+	}
+	input := &redshift.GetClusterCredentialsInput{
+		ClusterIdentifier: aws.String(settings.ClusterIdentifier),
+		DbUser:            aws.String(dbUser),
+		DbName:            aws.String(settings.Database),
+	}
+	if durationSeconds > 0 {
+		input.DurationSeconds = aws.Int64(durationSeconds)
+	}
+	c.metrics().IncCall("GetClusterCredentials")
+	out, err := c.ManagementClient.GetClusterCredentialsWithContext(ctx, input)
+	if err != nil {
+		return nil, permissionError(err, err)
+	}
+	return out, nil
+}
+
+// Settings returns the settings the API was created with
+func (c *API) Settings() *models.RedshiftDataSourceSettings {
+	return c.settings
+}
+
+// StatementInput extends the generic query input with Redshift specific options
+type StatementInput struct {
+	api.ExecuteQueryInput
+	// Parameters are bound to the named placeholders (e.g. :name) of the query
+	Parameters map[string]string
+	// StatementName identifies the query in the Redshift console. If empty, a name is generated
+	// from the panel set with ContextWithPanel, if any.
+	StatementName string
+	// Database overrides the database of the datasource for this statement only, which is
+	// connected to run it. Other databases can still be referenced by the query (e.g. db.schema.table).
+	Database string
+	// MaxRows is the number of records ExecuteAndWait fetches at most, if greater than 0
+	MaxRows int
+	// SecretARN is the managed secret to run this statement with, instead of the credentials of the data source
+	SecretARN string
+	// ClientToken makes the submission idempotent: AWS runs the statement once for the submissions with
+	// the same token. If empty, a token is generated for the call and reused by its retries.
+	ClientToken string
+}
+
+// newClientToken returns a random UUID identifying a submission, so its retries aren't run twice
+func newClientToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("%w: unable to generate a client token: %v", api.ExecuteError, err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// maxStatementNameLength is the Data API limit for StatementName
+const maxStatementNameLength = 500
+
+type panelContextKey struct{}
+
+type panelContext struct {
+	dashboardUID string
+	panelID      int64
+}
+
+// ContextWithPanel stores the panel running the query so statements can be named after it
+func ContextWithPanel(ctx context.Context, dashboardUID string, panelID int64) context.Context {
+	return context.WithValue(ctx, panelContextKey{}, panelContext{dashboardUID: dashboardUID, panelID: panelID})
+}
+
+// statementName returns the name given to a statement, truncated to the Data API limit
+func statementName(ctx context.Context, name string) *string {
+	if name == "" {
+		panel, ok := ctx.Value(panelContextKey{}).(panelContext)
+		if !ok {
+			return nil
+		}
+		name = fmt.Sprintf("grafana-%s-%d", panel.dashboardUID, panel.panelID)
+	}
+	if len(name) > maxStatementNameLength {
+		name = name[:maxStatementNameLength]
+	}
+	return aws.String(name)
+}
+
+func (c *API) Execute(ctx context.Context, input *api.ExecuteQueryInput) (*api.ExecuteQueryOutput, error) {
+	return c.ExecuteStatement(ctx, &StatementInput{ExecuteQueryInput: *input})
+}
+
+// ExecuteStatement submits a single statement. Each statement runs in a new session: the SessionId and
+// SessionKeepAliveSeconds parameters of the Data API are not available in the aws-sdk-go version in use.
+func (c *API) ExecuteStatement(ctx context.Context, input *StatementInput) (*api.ExecuteQueryOutput, error) {
+	commonInput := c.apiInput(ctx)
+	redshiftInput := &redshiftdataapiservice.ExecuteStatementInput{
+		ClusterIdentifier: commonInput.ClusterIdentifier,
+		WorkgroupName:     commonInput.WorkgroupName,
+		Database:          commonInput.Database,
+		DbUser:            commonInput.DbUser,
+		SecretArn:         commonInput.SecretARN,
+		Sql:               aws.String(input.Query),
+		Parameters:        sqlParameters(input.Parameters),
+		StatementName:     statementName(ctx, input.StatementName),
+		WithEvent:         aws.Bool(c.settings.WithEvent),
+	}
+	if input.Database != "" {
+		redshiftInput.Database = aws.String(input.Database)
+	}
+	if input.SecretARN != "" {
+		if err := validateSecretARN(input.SecretARN); err != nil {
+			return nil, fmt.Errorf("%w: %v", api.ExecuteError, err)
+		}
+		redshiftInput.SecretArn, redshiftInput.DbUser = aws.String(input.SecretARN), nil
+	}
+	token := input.ClientToken
+	if token == "" {
+		var err error
+		if token, err = newClientToken(); err != nil {
+			return nil, err
+		}
+	}
+	// The SDK generates a new token on every call otherwise, so the retries could run the statement again
+	redshiftInput.ClientToken = aws.String(token)
+
+	var output *redshiftdataapiservice.ExecuteStatementOutput
+	submit := func() error {
+		return c.withRetry(ctx, "ExecuteStatement", func(ctx aws.Context) (err error) {
+			output, err = c.DataClient.ExecuteStatementWithContext(ctx, redshiftInput)
+			return err
+		})
+	}
+	err := submit()
+	if err != nil && c.settings.AutoResume && redshiftInput.WorkgroupName == nil && isClusterPausedError(err) {
+		if err := c.resumeCluster(ctx); err != nil {
+			return nil, err
+		}
+		err = submit()
+	}
+	if err != nil {
+		c.logger().Debug("failed to submit the statement", "query hash", queryHash(input.Query), "error", err.Error())
+		return nil, c.executeError(ctx, err)
+	}
+
+	if output == nil || output.Id == nil {
+		return nil, fmt.Errorf("%w: the response has no statement ID", api.ExecuteError)
+	}
+	c.logger().Debug("statement submitted", "query ID", *output.Id, "query hash", queryHash(input.Query))
+	c.trackStatement(ctx, *output.Id)
+	return &api.ExecuteQueryOutput{ID: *output.Id}, nil
+}
+
+// BatchExecute runs the queries as a single transaction. The returned ID can be used with
+// StatementStatus, which reports the status of each query in SubStatements.
+func (c *API) BatchExecute(ctx context.Context, queries []string) (*api.ExecuteQueryOutput, error) {
+	commonInput := c.apiInput(ctx)
+	redshiftInput := &redshiftdataapiservice.BatchExecuteStatementInput{
+		ClusterIdentifier: commonInput.ClusterIdentifier,
+		WorkgroupName:     commonInput.WorkgroupName,
+		Database:          commonInput.Database,
+		DbUser:            commonInput.DbUser,
+		SecretArn:         commonInput.SecretARN,
+		Sqls:              aws.StringSlice(queries),
+		StatementName:     statementName(ctx, ""),
+		WithEvent:         aws.Bool(c.settings.WithEvent),
+	}
+	token, err := newClientToken()
+	if err != nil {
+		return nil, err
+	}
+	redshiftInput.ClientToken = aws.String(token)
+
+	var output *redshiftdataapiservice.BatchExecuteStatementOutput
+	err = c.withRetry(ctx, "BatchExecuteStatement", func(ctx aws.Context) (err error) {
+		output, err = c.DataClient.BatchExecuteStatementWithContext(ctx, redshiftInput)
+		return err
+	})
+	if err != nil {
+		c.logger().Debug("failed to submit the batch", "queries", len(queries), "error", err.Error())
+		return nil, c.executeError(ctx, err)
+	}
+
+	if output == nil || output.Id == nil {
+		return nil, fmt.Errorf("%w: the response has no statement ID", api.ExecuteError)
+	}
+	c.logger().Debug("batch submitted", "query ID", *output.Id, "queries", len(queries))
+	c.trackStatement(ctx, *output.Id)
+	return &api.ExecuteQueryOutput{ID: *output.Id}, nil
+}
+
+type cancelOnDoneKey struct{}
+
+// WithStatementCancellation returns a context with which the submitted statements are canceled on the cluster
+// when the context is canceled, e.g. when the dashboard running the query is closed. ExecuteAndWait and the
+// queries of the driver use it. The statements submitted with other contexts, e.g. by Execute, Copy or Unload,
+// keep running after the context is done.
+func WithStatementCancellation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cancelOnDoneKey{}, true)
+}
+
+// trackStatement cancels the statement on the cluster if the context it was submitted with is canceled
+// before it's done, when the context was created with WithStatementCancellation. The cancellation is best-effort.
+func (c *API) trackStatement(ctx context.Context, id string) {
+	if cancelOnDone, _ := ctx.Value(cancelOnDoneKey{}).(bool); !cancelOnDone {
+		return
+	}
+	c.inflight.track(ctx, id, func() {
+		if err := c.StopWithContext(context.Background(), &api.ExecuteQueryOutput{ID: id}); err != nil {
+			c.logger().Debug("failed to stop the statement", "query ID", id, "error", err.Error())
+		}
+	})
+}
+
+// executeError wraps an error returned when submitting statements
+func (c *API) executeError(ctx aws.Context, err error) error {
+	if isCanceled(ctx, err) {
+		return fmt.Errorf("%w: %v", CanceledError, err)
+	}
+	if c.settings.UseManagedSecret && isAuthError(err) {
+		// The secret may have been rotated, make sure it's fetched again
+		c.secrets.invalidate(c.settings.ManagedSecret.ARN)
+	}
+	if isClusterPausedError(err) || isClusterResumingError(err) {
+		return fmt.Errorf("%w: %v", ClusterPausedError, err)
+	}
+	return c.clusterNotFoundError(ctx, err, permissionError(err, fmt.Errorf("%w: %v", api.ExecuteError, err)))
+}
+
+// sqlParameters returns the parameters sorted by name, or nil if there are none
+// since the Data API rejects an empty list
+func sqlParameters(params map[string]string) []*redshiftdataapiservice.SqlParameter {
+	if len(params) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make([]*redshiftdataapiservice.SqlParameter, 0, len(names))
+	for _, name := range names {
+		res = append(res, &redshiftdataapiservice.SqlParameter{
+			Name:  aws.String(name),
+			Value: aws.String(params[name]),
+		})
+	}
+	return res
+}
+
+// StatementStatus extends the generic query status with Redshift specific details
+type StatementStatus struct {
+	api.ExecuteQueryStatus
+	// SubStatements are the statuses of each statement of a multi-statement query
+	SubStatements []SubStatementStatus
+	// FailedSubStatement is the index of the first failed sub-statement, or -1 if none failed
+	FailedSubStatement int
+	// Stats are only populated once they're known, so they're zero for queries in progress
+	Stats QueryStats
+	// Phase tells queued statements apart from running ones
+	Phase Phase
+	// HasResultSet is false for statements without a result (e.g. VACUUM, ANALYZE or COPY),
+	// whose result must not be fetched
+	HasResultSet bool
+	// RedshiftPid is the process ID of the session on the cluster and RedshiftQueryID the ID of the query,
+	// to find the statement in the system tables (e.g. stl_query). They're 0 until the statement has started.
+	RedshiftPid     int64
+	RedshiftQueryID int64
+}
+
+// Phase groups the Data API statuses by progress
+type Phase string
+
+const (
+	// PhaseQueued is for SUBMITTED and PICKED statements, waiting to be run
+	PhaseQueued Phase = "queued"
+	// PhaseRunning is for STARTED statements
+	PhaseRunning Phase = "running"
+	// PhaseFinished is for statements that succeeded
+	PhaseFinished Phase = "finished"
+	// PhaseFailed is for FAILED and ABORTED statements
+	PhaseFailed Phase = "failed"
+	// PhaseCanceled is for the statements ABORTED because they were stopped with Stop or StopWithContext
+	PhaseCanceled Phase = "canceled"
+)
+
+func statementPhase(state string) Phase {
+	switch state {
+	case redshiftdataapiservice.StatusStringSubmitted, redshiftdataapiservice.StatusStringPicked:
+		return PhaseQueued
+	case redshiftdataapiservice.StatusStringFinished:
+		return PhaseFinished
+	case redshiftdataapiservice.StatusStringFailed, redshiftdataapiservice.StatusStringAborted:
+		return PhaseFailed
+	default:
+		return PhaseRunning
+	}
+}
+
+type QueryStats struct {
+	Duration time.Duration
+	// ResultRows is the number of rows returned or affected by the query
+	ResultRows int64
+	// ResultSize is the size in bytes of the result
+	ResultSize int64
+}
+
+type SubStatementStatus struct {
+	ID    string
+	State string
+	Error string
+}
+
+func (c *API) Status(ctx aws.Context, output *api.ExecuteQueryOutput) (*api.ExecuteQueryStatus, error) {
+	status, err := c.StatementStatus(ctx, output)
+	if status == nil {
+		return nil, err
+	}
+	return &status.ExecuteQueryStatus, err
+}
+
+// A statement may not be found right after it's submitted, until its ID is propagated.
+// DescribeStatement is retried a few times before reporting it as not found.
+var (
+	statementNotFoundRetries = 3
+	statementNotFoundDelay   = 100 * time.Millisecond
+)
+
+func (c *API) StatementStatus(ctx aws.Context, output *api.ExecuteQueryOutput) (*StatementStatus, error) {
+	statusResp, err := c.describeStatement(ctx, output.ID)
+	if err != nil {
+		if isCanceled(ctx, err) {
+			return nil, fmt.Errorf("%w: %v", CanceledError, err)
+		}
+		if isStatementNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %v", StatementNotFoundError, err)
+		}
+		return nil, permissionError(err, fmt.Errorf("%w: %v", api.StatusError, err))
+	}
+	if statusResp == nil || statusResp.Status == nil {
+		return nil, fmt.Errorf("%w: the response of statement %s has no status", api.StatusError, output.ID)
+	}
+
+	subStatements := []SubStatementStatus{}
+	failedSubStatement := -1
+	for i, sub := range statusResp.SubStatements {
+		if sub == nil {
+			continue
+		}
+		subStatus := SubStatementStatus{
+			ID:    aws.StringValue(sub.Id),
+			State: aws.StringValue(sub.Status),
+			Error: aws.StringValue(sub.Error),
+		}
+		if failedSubStatement == -1 && subStatus.State == redshiftdataapiservice.StatementStatusStringFailed {
+			failedSubStatement = i
+		}
+		subStatements = append(subStatements, subStatus)
+	}
+
+	var finished bool
+	state := *statusResp.Status
+	phase := statementPhase(state)
+	switch {
+	case state == redshiftdataapiservice.StatusStringAborted && c.canceled.has(output.ID):
+		// The statement was stopped on purpose, it's done without a result rather than failed
+		finished, phase = true, PhaseCanceled
+	case state == redshiftdataapiservice.StatusStringFailed,
+		state == redshiftdataapiservice.StatusStringAborted:
+		finished = true
+		if failedSubStatement != -1 {
+			// Point to the statement that broke rather than to the generic parent error
+			err = fmt.Errorf("statement %d failed: %s", failedSubStatement+1, subStatements[failedSubStatement].Error)
+		} else {
+			err = syntaxError(newStatementError(state, statusResp.Error), aws.StringValue(statusResp.QueryString))
+		}
+	case state == redshiftdataapiservice.StatusStringFinished:
+		finished = true
+	default:
+		finished = false
+	}
+	if finished {
+		c.inflight.remove(output.ID)
+	}
+
+	return &StatementStatus{
+		ExecuteQueryStatus: api.ExecuteQueryStatus{
+			ID:       output.ID,
+			State:    state,
+			Finished: finished,
+		},
+		SubStatements:      subStatements,
+		FailedSubStatement: failedSubStatement,
+		Phase:              phase,
+		HasResultSet:       aws.BoolValue(statusResp.HasResultSet) && phase != PhaseCanceled,
+		RedshiftPid:        aws.Int64Value(statusResp.RedshiftPid),
+		RedshiftQueryID:    aws.Int64Value(statusResp.RedshiftQueryId),
+		Stats: QueryStats{
+			// The Data API reports the duration in nanoseconds
+			Duration:   time.Duration(aws.Int64Value(statusResp.Duration)),
+			ResultRows: aws.Int64Value(statusResp.ResultRows),
+			ResultSize: aws.Int64Value(statusResp.ResultSize),
+		},
+	}, err
+}
+
+func (c *API) describeStatement(ctx aws.Context, id string) (*redshiftdataapiservice.DescribeStatementOutput, error) {
+	var statusResp *redshiftdataapiservice.DescribeStatementOutput
+	for attempt := 0; ; attempt++ {
+		err := c.withRetry(ctx, "DescribeStatement", func(ctx aws.Context) (err error) {
+			statusResp, err = c.DataClient.DescribeStatementWithContext(ctx, &redshiftdataapiservice.DescribeStatementInput{
+				Id: aws.String(id),
+			})
+			return err
+		})
+		if err == nil || !isStatementNotFoundError(err) || attempt >= statementNotFoundRetries {
+			return statusResp, err
+		}
+		c.logger().Debug("statement not found yet, retrying", "query ID", id)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(statementNotFoundDelay):
+		}
+	}
+}
+
+// PollOptions describe how often the status of a statement is polled. The first polls are quick to
+// catch short queries, then the interval grows by Factor up to Max to save calls on long queries.
+// The zero values use the settings, or the defaults.
+type PollOptions struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+// pollOptions completes the options with the settings and the defaults
+func (c *API) pollOptions(options PollOptions) PollOptions {
+	res := PollOptions{Min: defaultPollInterval, Max: maxPollInterval, Factor: defaultPollFactor}
+	if c.settings != nil {
+		if c.settings.PollInterval > 0 {
+			res.Min = time.Duration(c.settings.PollInterval) * time.Millisecond
+		}
+		if c.settings.MaxPollInterval > 0 {
+			res.Max = time.Duration(c.settings.MaxPollInterval) * time.Millisecond
+		}
+		if c.settings.PollFactor >= 1 {
+			res.Factor = c.settings.PollFactor
+		}
+	}
+	if options.Min > 0 {
+		res.Min = options.Min
+	}
+	if options.Max > 0 {
+		res.Max = options.Max
+	}
+	if options.Factor >= 1 {
+		res.Factor = options.Factor
+	}
+	if res.Max < res.Min {
+		res.Max = res.Min
+	}
+	return res
+}
+
+// WaitOnQuery polls the statement status until it finishes, fails or the context is done.
+// The polling interval starts at pollInterval (or the setting if 0) and grows as described by WaitOnQueryWithPolling.
+func (c *API) WaitOnQuery(ctx aws.Context, output *api.ExecuteQueryOutput, pollInterval time.Duration) (*StatementStatus, error) {
+	return c.WaitOnQueryWithPolling(ctx, output, PollOptions{Min: pollInterval})
+}
+
+// WaitOnQueryWithPolling is WaitOnQuery polling with the given options. The wait between two polls
+// ends as soon as the context is done.
+func (c *API) WaitOnQueryWithPolling(ctx aws.Context, output *api.ExecuteQueryOutput, options PollOptions) (*StatementStatus, error) {
+	options = c.pollOptions(options)
+	// The statement is stopped below when the context is canceled
+	c.inflight.setWaited(output.ID, true)
+	defer c.inflight.setWaited(output.ID, false)
+	b := backoff.Backoff{
+		Min:    options.Min,
+		Max:    options.Max,
+		Factor: options.Factor,
+	}
+	// The timer is reset before each wait, unlike time.After it's released when the context is done
+	poll := time.NewTimer(options.Max)
+	poll.Stop()
+	defer poll.Stop()
+	var state string
+	var timeout <-chan time.Time
+	if c.settings != nil && c.settings.QueryTimeout > 0 {
+		timer := time.NewTimer(time.Duration(c.settings.QueryTimeout) * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		status, err := c.StatementStatus(ctx, output)
+		if err != nil {
+			c.logger().Debug("statement failed", "query ID", output.ID, "error", err.Error())
+			return status, err
+		}
+		if status.State != state {
+			state = status.State
+			c.logger().Debug("statement status changed", "query ID", output.ID, "status", state)
+		}
+		if status.Finished {
+			c.metrics().ObserveQuery(status.Stats)
+			c.logger().Debug("statement finished", "query ID", output.ID, "duration", status.Stats.Duration.String(), "redshift pid", status.RedshiftPid, "redshift query ID", status.RedshiftQueryID)
+			return status, nil
+		}
+		poll.Reset(b.Duration())
+		select {
+		case <-timeout:
+			// The cancellation is best-effort, the timeout is reported even if it fails
+			if stopErr := c.StopWithContext(context.Background(), output); stopErr != nil {
+				c.logger().Debug("failed to stop the statement", "query ID", output.ID, "error", stopErr.Error())
+			}
+			return status, fmt.Errorf("%w after %ds", TimeoutError, c.settings.QueryTimeout)
+		case <-ctx.Done():
+			err := ctx.Err()
+			if errors.Is(err, context.Canceled) {
+				// Don't leave the statement running on the cluster if the request was canceled
+				if stopErr := c.StopWithContext(context.Background(), output); stopErr != nil {
+					c.logger().Debug("failed to stop the statement", "query ID", output.ID, "error", stopErr.Error())
+				}
+			}
+			return status, err
+		case <-poll.C:
+		}
+	}
+}
+
+// Stop cancels the statement. Its signature is defined by the sql api interface
+// so prefer StopWithContext when a context is available.
+func (c *API) Stop(output *api.ExecuteQueryOutput) error {
+	return c.StopWithContext(context.Background(), output)
+}
+
+func (c *API) StopWithContext(ctx aws.Context, output *api.ExecuteQueryOutput) error {
+	c.inflight.remove(output.ID)
+	c.metrics().IncCall("CancelStatement")
+	// Recorded first so a status described while the cancel completes isn't reported as a failure
+	c.canceled.add(output.ID)
+	_, err := c.DataClient.CancelStatementWithContext(ctx, &redshiftdataapiservice.CancelStatementInput{
+		Id: &output.ID,
+	})
+	if err != nil {
+		c.canceled.remove(output.ID)
+		return fmt.Errorf("%w: %v", api.StopError, err)
+	}
+	c.logger().Debug("statement canceled", "query ID", output.ID)
+	return nil
+}
+
+// GetResult fetches every page of the statement result. If maxRows is greater than 0,
+// pagination stops once that number of records has been fetched.
+// The column metadata is returned with every page so only the one of the first page is kept.
+// The records are Data API fields, GetResultSet returns plain Go values instead.
+func (c *API) GetResult(ctx aws.Context, id string, maxRows int) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	res, _, err := c.getResult(ctx, id, maxRows)
+	return res, err
+}
+
+// getResult is GetResult also returning whether records were left out because of maxRows
+func (c *API) getResult(ctx aws.Context, id string, maxRows int) (*redshiftdataapiservice.GetStatementResultOutput, bool, error) {
+	input := &redshiftdataapiservice.GetStatementResultInput{
+		Id: aws.String(id),
+	}
+	var res *redshiftdataapiservice.GetStatementResultOutput
+	truncated := false
+	isFinished := false
+	for !isFinished {
+		out, err := c.resultPage(ctx, input, res == nil)
+		if err != nil {
+			return nil, false, err
+		}
+		if res == nil {
+			res = &redshiftdataapiservice.GetStatementResultOutput{
+				ColumnMetadata: out.ColumnMetadata,
+				TotalNumRows:   out.TotalNumRows,
+				Records:        [][]*redshiftdataapiservice.Field{},
+			}
+		}
+		res.Records = append(res.Records, out.Records...)
+		input.NextToken = out.NextToken
+		if input.NextToken == nil || *input.NextToken == "" {
+			isFinished = true
+		}
+		if maxRows > 0 && len(res.Records) >= maxRows {
+			truncated = len(res.Records) > maxRows || !isFinished
+			res.Records = res.Records[:maxRows]
+			isFinished = true
+		}
+	}
+	return res, truncated, nil
+}
+
+// ResultPage fetches the page of the statement result following nextToken, the first page if nil, e.g. to
+// read a large result page by page. Like GetResult, it retries the pages failing transiently and maps the errors.
+func (c *API) ResultPage(ctx aws.Context, id string, nextToken *string) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	return c.resultPage(ctx, &redshiftdataapiservice.GetStatementResultInput{Id: aws.String(id), NextToken: nextToken}, nextToken == nil)
+}
+
+// resultPage fetches a page of the statement result, first tells whether it's the first page
+func (c *API) resultPage(ctx aws.Context, input *redshiftdataapiservice.GetStatementResultInput, first bool) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	var out *redshiftdataapiservice.GetStatementResultOutput
+	// Pages can be fetched again with the same token, so a page failing transiently is retried alone
+	err := c.withTransientRetry(ctx, "GetStatementResult", func(ctx aws.Context) (err error) {
+		out, err = c.DataClient.GetStatementResultWithContext(ctx, input)
+		return err
+	})
+	if err != nil {
+		if awsErrorCode(err) == redshiftdataapiservice.ErrCodeResourceNotFoundException && first {
+			// DDL statements and the like don't have a result to fetch
+			return nil, fmt.Errorf("%w: %v", NoResultSetError, err)
+		}
+		if isResultTooLargeError(err) {
+			return nil, fmt.Errorf("%w: %v", ResultTooLargeError, err)
+		}
+		if isExpiredTokenError(err) {
+			return nil, fmt.Errorf("%w: %v", ExpiredResultTokenError, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ResultError, err)
+	}
+	return out, nil
+}
+
+func (c *API) Regions(ctx aws.Context) ([]string, error) {
+	out, err := c.EC2Client.DescribeRegionsWithContext(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == "UnauthorizedOperation" {
+			// The role is not allowed to describe regions, use the list known by the SDK instead
+			c.logger().Debug("unable to describe regions, using the static list", "error", err.Error())
+			return serviceRegions(redshift.EndpointsID), nil
+		}
+		return nil, fmt.Errorf("failed to describe regions: %w", err)
+	}
+	regions := []string{}
+	for _, r := range out.Regions {
+		if r != nil && r.RegionName != nil {
+			regions = append(regions, *r.RegionName)
+		}
+	}
+	return sortedUnique(regions), nil
+}
+
+func sortedUnique(values []string) []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			res = append(res, v)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// StatementSummary describes a statement run through the Data API
+type StatementSummary struct {
+	ID        string
+	Name      string
+	Query     string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ListStatementsFilter selects the statements returned by ListStatements
+type ListStatementsFilter struct {
+	// Status of the statements (e.g. STARTED), all statements are returned if empty
+	Status string
+	// StatementNamePrefix only returns the statements whose name starts with it, e.g. "grafana-" for the
+	// statements run by Grafana panels
+	StatementNamePrefix string
+	// CurrentSessionOnly only returns the statements run in the current IAM session of the datasource
+	// credentials instead of every statement run with their IAM role, the default
+	CurrentSessionOnly bool
+}
+
+// ListStatements returns the statements run recently, for example to find the ones that are still running
+func (c *API) ListStatements(ctx aws.Context, filter ListStatementsFilter) ([]StatementSummary, error) {
+	input := &redshiftdataapiservice.ListStatementsInput{
+		RoleLevel: aws.Bool(!filter.CurrentSessionOnly),
+	}
+	if filter.Status != "" {
+		input.Status = aws.String(filter.Status)
+	}
+	if filter.StatementNamePrefix != "" {
+		input.StatementName = aws.String(filter.StatementNamePrefix)
+	}
+	res := []StatementSummary{}
+	for pages := 1; ; pages++ {
+		var out *redshiftdataapiservice.ListStatementsOutput
+		err := c.withRetry(ctx, "ListStatements", func(ctx aws.Context) (err error) {
+			out, err = c.DataClient.ListStatementsWithContext(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, listError(ctx, ListStatementsError, err)
+		}
+		for _, st := range out.Statements {
+			if st == nil {
+				continue
+			}
+			res = append(res, StatementSummary{
+				ID:        aws.StringValue(st.Id),
+				Name:      aws.StringValue(st.StatementName),
+				Query:     aws.StringValue(st.QueryString),
+				Status:    aws.StringValue(st.Status),
+				CreatedAt: aws.TimeValue(st.CreatedAt),
+				UpdatedAt: aws.TimeValue(st.UpdatedAt),
+			})
+		}
+		if out.NextToken == nil {
+			break
+		}
+		if pages >= c.maxPages() {
+			return res, c.pageLimitError("ListStatements", pages)
+		}
+		input.NextToken = out.NextToken
+	}
+	return res, nil
+}
+
+func (c *API) Databases(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	commonInput := c.apiInput(ctx)
+	input := &redshiftdataapiservice.ListDatabasesInput{
+		ClusterIdentifier: commonInput.ClusterIdentifier,
+		WorkgroupName:     commonInput.WorkgroupName,
+		Database:          commonInput.Database,
+		DbUser:            commonInput.DbUser,
+		SecretArn:         commonInput.SecretARN,
+	}
+	isFinished := false
+	res := []string{}
+	for pages := 1; !isFinished; pages++ {
+		var out *redshiftdataapiservice.ListDatabasesOutput
+		err := c.withRetry(ctx, "ListDatabases", func(ctx aws.Context) (err error) {
+			out, err = c.DataClient.ListDatabasesWithContext(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, c.clusterNotFoundError(ctx, err, listError(ctx, ListDatabasesError, err))
+		}
+		input.NextToken = out.NextToken
+		for _, sc := range out.Databases {
+			if sc != nil {
+				res = append(res, *sc)
+			}
+		}
+		if input.NextToken == nil {
+			isFinished = true
+		} else if pages >= c.maxPages() {
+			sort.Strings(res)
+			return res, c.pageLimitError("ListDatabases", pages)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// cacheKey identifies a resource for the current connection. It includes the credentials
+// so that resources are never shared between different database users or secrets.
+func (c *API) cacheKey(ctx context.Context, resource string, args ...string) string {
+	in := c.apiInput(ctx)
+	parts := []string{
+		resource,
+		aws.StringValue(in.ClusterIdentifier),
+		aws.StringValue(in.WorkgroupName),
+		aws.StringValue(in.Database),
+		aws.StringValue(in.DbUser),
+		aws.StringValue(in.SecretARN),
+	}
+	return strings.Join(append(parts, args...), "/")
+}
+
+// ClearCache removes the cached schemas, tables and columns
+func (c *API) ClearCache() {
+	c.cache.clear()
+}
+
+// Close releases the cached schemas, tables, columns and secrets of a disposed datasource.
+// It's safe to call multiple times, the API can still be used afterwards but starts with empty caches.
+func (c *API) Close() error {
+	c.cache.clear()
+	c.secrets.clear()
+	return nil
+}
+
+// catalogDatabases returns the database requested in the options, or the default one.
+// When another database is requested, the default one is returned as the database to connect to
+// since the Data API can browse the catalog of other databases of the same cluster.
+func catalogDatabases(options sqlds.Options, defaultDatabase *string) (database *string, connectedDatabase *string) {
+	if db := options["database"]; db != "" && db != awsModels.DefaultKey && db != aws.StringValue(defaultDatabase) {
+		return aws.String(db), defaultDatabase
+	}
+	return defaultDatabase, nil
+}
+
+var likePatternEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLikePattern escapes the LIKE wildcards of a name so that pattern fields match it literally,
+// otherwise a schema like "my_schema" would also match "myxschema"
+func escapeLikePattern(name string) string {
+	return likePatternEscaper.Replace(name)
+}
+
+// schemaPattern returns the pattern of the schemas to list, nil to list them all. The schemaPattern option is a
+// LIKE pattern as tablePattern, while the schemaPrefix option is matched literally, e.g. what is typed in an editor.
+func schemaPattern(options sqlds.Options) *string {
+	if pattern := options["schemaPattern"]; pattern != "" {
+		return aws.String(pattern)
+	}
+	if prefix := options["schemaPrefix"]; prefix != "" {
+		return aws.String(escapeLikePattern(prefix) + "%")
+	}
+	return nil
+}
+
+// identifierName returns the name of an identifier. Quoted identifiers (e.g. "MixedCase") are unquoted
+// since the Data API expects the names as they are stored in the catalog.
+func identifierName(identifier string) string {
+	if len(identifier) >= 2 && strings.HasPrefix(identifier, `"`) && strings.HasSuffix(identifier, `"`) {
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], `""`, `"`)
+	}
+	return identifier
+}
+
+// PageInput requests a page of a list. NextToken is empty for the first page, PageSize is optional.
+type PageInput struct {
+	NextToken string
+	PageSize  int64
+}
+
+// Page is a page of a list. NextToken is an opaque token to request the next page, it's empty on the last one.
+type Page struct {
+	Items     []string
+	NextToken string
+}
+
+func pageInput(page PageInput) (nextToken *string, maxResults *int64) {
+	if page.NextToken != "" {
+		nextToken = aws.String(page.NextToken)
+	}
+	if page.PageSize > 0 {
+		maxResults = aws.Int64(page.PageSize)
+	}
+	return nextToken, maxResults
 }
 
-func New(sessionCache *awsds.SessionCache, settings awsModels.Settings) (api.AWSAPI, error) {
-	redshiftSettings := settings.(*models.RedshiftDataSourceSettings)
+const defaultMaxPages = 100
 
-	httpClientProvider := sdkhttpclient.NewProvider()
-	httpClientOptions, err := redshiftSettings.Config.HTTPClientOptions()
-	if err != nil {
-		backend.Logger.Error("failed to create HTTP client options", "error", err.Error())
-		return nil, err
+func (c *API) maxPages() int {
+	if c.settings != nil && c.settings.MaxPages > 0 {
+		return c.settings.MaxPages
 	}
-	httpClient, err := httpClientProvider.New(httpClientOptions)
-	if err != nil {
-		backend.Logger.Error("failed to create HTTP client", "error", err.Error())
-		return nil, err
+	return defaultMaxPages
+}
+
+// pageLimitError is returned with the items fetched so far when a list has more pages than allowed
+func (c *API) pageLimitError(operation string, pages int) error {
+	c.logger().Warn("list stopped after reaching the maximum number of pages", "operation", operation, "pages", pages)
+	return fmt.Errorf("%w: %s stopped after %d pages", PageLimitError, operation, pages)
+}
+
+// skippedEntries reports the entries of a list page that were dropped because they are nil or have no name.
+// They are logged, and in strict mode an IncompleteListError is returned so the list isn't taken as complete.
+func (c *API) skippedEntries(operation string, skipped int) error {
+	if skipped == 0 {
+		return nil
+	}
+	c.logger().Warn("list entries skipped", "operation", operation, "skipped", skipped)
+	if c.settings != nil && c.settings.StrictLists {
+		return fmt.Errorf("%w: %s returned %d entries without a name", IncompleteListError, operation, skipped)
 	}
+	return nil
+}
 
-	sess, err := sessionCache.GetSession(awsds.SessionConfig{
-		Settings:      redshiftSettings.AWSDatasourceSettings,
-		HTTPClient:    httpClient,
-		UserAgentName: aws.String("Redshift"),
+// Schemas, Tables, Columns, Databases, Secrets and ListStatements fetch every page of the list up to the
+// maximum number of pages. Past it, the items fetched so far are returned together with a PageLimitError.
+func (c *API) Schemas(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	database, _ := catalogDatabases(options, c.apiInput(ctx).Database)
+	key := c.cacheKey(ctx, "schemas", aws.StringValue(database), aws.StringValue(schemaPattern(options)))
+	if res, ok := c.cache.get(key); ok {
+		return res, nil
+	}
+	return c.flights.do(ctx, key, func() ([]string, error) {
+		res := []string{}
+		page := PageInput{}
+		for pages := 1; ; pages++ {
+			out, err := c.SchemasPage(ctx, options, page)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, out.Items...)
+			if out.NextToken == "" {
+				break
+			}
+			if pages >= c.maxPages() {
+				return res, c.pageLimitError("ListSchemas", pages)
+			}
+			page.NextToken = out.NextToken
+		}
+		c.cache.set(key, res)
+		return res, nil
+	})
+}
+
+// SchemasPage returns a page of the schemas, so they can be loaded incrementally
+func (c *API) SchemasPage(ctx aws.Context, options sqlds.Options, page PageInput) (*Page, error) {
+	commonInput := c.apiInput(ctx)
+	database, connectedDatabase := catalogDatabases(options, commonInput.Database)
+	input := &redshiftdataapiservice.ListSchemasInput{
+		ClusterIdentifier: commonInput.ClusterIdentifier,
+		WorkgroupName:     commonInput.WorkgroupName,
+		Database:          database,
+		ConnectedDatabase: connectedDatabase,
+		DbUser:            commonInput.DbUser,
+		SecretArn:         commonInput.SecretARN,
+		SchemaPattern:     schemaPattern(options),
+	}
+	input.NextToken, input.MaxResults = pageInput(page)
+	var out *redshiftdataapiservice.ListSchemasOutput
+	err := c.withRetry(ctx, "ListSchemas", func(ctx aws.Context) (err error) {
+		out, err = c.DataClient.ListSchemasWithContext(ctx, input)
+		return err
 	})
 	if err != nil {
+		return nil, c.clusterNotFoundError(ctx, err, listError(ctx, ListSchemasError, err))
+	}
+	res := &Page{Items: []string{}, NextToken: aws.StringValue(out.NextToken)}
+	skipped := 0
+	for _, sc := range out.Schemas {
+		if sc == nil {
+			skipped++
+			continue
+		}
+		res.Items = append(res.Items, *sc)
+	}
+	if err := c.skippedEntries("ListSchemas", skipped); err != nil {
 		return nil, err
 	}
+	return res, nil
+}
 
-	return &API{
-		DataClient:       redshiftdataapiservice.New(sess),
-		SecretsClient:    secretsmanager.New(sess),
-		ManagementClient: redshift.New(sess),
-		settings:         redshiftSettings,
-	}, nil
+func (c *API) Tables(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	database, _ := catalogDatabases(options, c.apiInput(ctx).Database)
+	key := c.cacheKey(ctx, "tables", aws.StringValue(database), c.tablesSchema(options), options["tablePattern"])
+	if res, ok := c.cache.get(key); ok {
+		return res, nil
+	}
+	return c.flights.do(ctx, key, func() ([]string, error) {
+		res := []string{}
+		page := PageInput{}
+		for pages := 1; ; pages++ {
+			out, err := c.TablesPage(ctx, options, page)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, out.Items...)
+			if out.NextToken == "" {
+				break
+			}
+			if pages >= c.maxPages() {
+				return res, c.pageLimitError("ListTables", pages)
+			}
+			page.NextToken = out.NextToken
+		}
+		c.cache.set(key, res)
+		return res, nil
+	})
 }
 
-type apiInput struct {
-	ClusterIdentifier *string
-	Database          *string
-	DbUser            *string
-	SecretARN         *string
+// tablesSchema returns the schema of the options, the default schema of the settings if it's not specified
+func (c *API) tablesSchema(options sqlds.Options) string {
+	if schema := identifierName(options["schema"]); schema != "" {
+		return schema
+	}
+	// We use the "public" schema by default if not configured
+	if c.settings != nil && c.settings.DefaultSchema != "" {
+		return identifierName(c.settings.DefaultSchema)
+	}
+	return "public"
 }
 
-func (c *API) apiInput() apiInput {
-	res := apiInput{
-		ClusterIdentifier: aws.String(c.settings.ClusterIdentifier),
-		Database:          aws.String(c.settings.Database),
+// TablesPage returns a page of the tables of a schema, so they can be loaded incrementally
+func (c *API) TablesPage(ctx aws.Context, options sqlds.Options, page PageInput) (*Page, error) {
+	tables, next, err := c.listTablesPage(ctx, options, page)
+	if err != nil {
+		return nil, err
 	}
-	if c.settings.UseManagedSecret {
-		res.SecretARN = aws.String(c.settings.ManagedSecret.ARN)
-	} else {
-		res.DbUser = aws.String(c.settings.DBUser)
+	res := &Page{Items: []string{}, NextToken: next}
+	for _, t := range tables {
+		res.Items = append(res.Items, t.Name)
 	}
-	return res
+	return res, nil
 }
 
-func (c *API) Execute(ctx context.Context, input *api.ExecuteQueryInput) (*api.ExecuteQueryOutput, error) {
-	commonInput := c.apiInput()
-	redshiftInput := &redshiftdataapiservice.ExecuteStatementInput{
+// TableInfo describes a table of a schema
+type TableInfo struct {
+	Name string
+	// Type is the type of the table as returned by the Data API, e.g. TABLE, VIEW or MATERIALIZED VIEW
+	Type string
+}
+
+// TablesWithType returns the tables of a schema with their type, so views can be told apart from tables.
+// Unlike Tables, the result is not cached.
+func (c *API) TablesWithType(ctx aws.Context, options sqlds.Options) ([]TableInfo, error) {
+	res := []TableInfo{}
+	page := PageInput{}
+	for pages := 1; ; pages++ {
+		tables, next, err := c.listTablesPage(ctx, options, page)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, tables...)
+		if next == "" {
+			break
+		}
+		if pages >= c.maxPages() {
+			return res, c.pageLimitError("ListTables", pages)
+		}
+		page.NextToken = next
+	}
+	return res, nil
+}
+
+func (c *API) listTablesPage(ctx aws.Context, options sqlds.Options, page PageInput) ([]TableInfo, string, error) {
+	tablePattern := options["tablePattern"]
+	commonInput := c.apiInput(ctx)
+	database, connectedDatabase := catalogDatabases(options, commonInput.Database)
+	input := &redshiftdataapiservice.ListTablesInput{
 		ClusterIdentifier: commonInput.ClusterIdentifier,
-		Database:          commonInput.Database,
+		WorkgroupName:     commonInput.WorkgroupName,
+		Database:          database,
+		ConnectedDatabase: connectedDatabase,
 		DbUser:            commonInput.DbUser,
 		SecretArn:         commonInput.SecretARN,
-		Sql:               aws.String(input.Query),
+		SchemaPattern:     aws.String(escapeLikePattern(c.tablesSchema(options))),
 	}
-
-	output, err := c.DataClient.ExecuteStatementWithContext(ctx, redshiftInput)
+	if tablePattern != "" {
+		input.TablePattern = aws.String(tablePattern)
+	}
+	input.NextToken, input.MaxResults = pageInput(page)
+	var out *redshiftdataapiservice.ListTablesOutput
+	err := c.withRetry(ctx, "ListTables", func(ctx aws.Context) (err error) {
+		out, err = c.DataClient.ListTablesWithContext(ctx, input)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", api.ExecuteError, err)
+		return nil, "", c.clusterNotFoundError(ctx, err, listError(ctx, ListTablesError, err))
 	}
-
-	return &api.ExecuteQueryOutput{ID: *output.Id}, nil
+	res := []TableInfo{}
+	skipped := 0
+	for _, t := range out.Tables {
+		if t == nil || t.Name == nil {
+			skipped++
+			continue
+		}
+		res = append(res, TableInfo{Name: *t.Name, Type: aws.StringValue(t.Type)})
+	}
+	if err := c.skippedEntries("ListTables", skipped); err != nil {
+		return nil, "", err
+	}
+	return res, aws.StringValue(out.NextToken), nil
 }
 
-func (c *API) Status(ctx aws.Context, output *api.ExecuteQueryOutput) (*api.ExecuteQueryStatus, error) {
-	statusResp, err := c.DataClient.DescribeStatementWithContext(ctx, &redshiftdataapiservice.DescribeStatementInput{
-		Id: aws.String(output.ID),
+// Columns returns the columns of a table. With the columnPrefix option, only the columns
+// whose name starts with the prefix, whatever its case, are returned.
+func (c *API) Columns(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	database, _ := catalogDatabases(options, c.apiInput(ctx).Database)
+	key := c.cacheKey(ctx, "columns", aws.StringValue(database), identifierName(options["schema"]), identifierName(options["table"]))
+	if res, ok := c.cache.get(key); ok {
+		return filterPrefix(res, options["columnPrefix"]), nil
+	}
+	res, err := c.flights.do(ctx, key, func() ([]string, error) {
+		columns, err := c.describeTable(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		res := []string{}
+		for _, column := range columns {
+			if column.Name != nil {
+				res = append(res, *column.Name)
+			}
+		}
+		c.cache.set(key, res)
+		return res, nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", api.StatusError, err)
+		return nil, err
 	}
+	return filterPrefix(res, options["columnPrefix"]), nil
+}
 
-	var finished bool
-	state := *statusResp.Status
-	switch state {
-	case redshiftdataapiservice.StatusStringFailed,
-		redshiftdataapiservice.StatusStringAborted:
-		finished = true
-		err = errors.New(*statusResp.Error)
-	case redshiftdataapiservice.StatusStringFinished:
-		finished = true
-	default:
-		finished = false
+// filterPrefix returns the names starting with the prefix, case insensitively. The names are not modified.
+func filterPrefix(names []string, prefix string) []string {
+	if prefix == "" {
+		return names
 	}
-
-	return &api.ExecuteQueryStatus{
-		ID:       output.ID,
-		State:    state,
-		Finished: finished,
-	}, err
+	prefix = strings.ToLower(prefix)
+	res := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			res = append(res, name)
+		}
+	}
+	return res
 }
 
-func (c *API) Stop(output *api.ExecuteQueryOutput) error {
-	_, err := c.DataClient.CancelStatement(&redshiftdataapiservice.CancelStatementInput{
-		Id: &output.ID,
-	})
+// ColumnsPage returns a page of the columns of a table, so they can be loaded incrementally
+func (c *API) ColumnsPage(ctx aws.Context, options sqlds.Options, page PageInput) (*Page, error) {
+	out, err := c.describeTablePage(ctx, options, page)
 	if err != nil {
-		return fmt.Errorf("%w: %v", err, api.StopError)
+		return nil, err
 	}
-	return nil
+	res := &Page{Items: []string{}, NextToken: aws.StringValue(out.NextToken)}
+	for _, col := range out.ColumnList {
+		if col != nil && col.Name != nil {
+			res.Items = append(res.Items, *col.Name)
+		}
+	}
+	return res, nil
 }
 
-func (c *API) Regions(aws.Context) ([]string, error) {
-	// List from https://docs.aws.amazon.com/general/latest/gr/redshift-service.html
-	return []string{
-		"us-east-2",
-		"us-east-1",
-		"us-west-1",
-		"us-west-2",
-		"af-south-1",
-		"ap-east-1",
-		"ap-south-1",
-		"ap-northeast-3",
-		"ap-northeast-2",
-		"ap-southeast-1",
-		"ap-southeast-2",
-		"ap-northeast-1",
-		"ca-central-1",
-		"eu-central-1",
-		"eu-west-1",
-		"eu-west-2",
-		"eu-south-1",
-		"eu-west-3",
-		"eu-north-1",
-		"me-south-1",
-		"sa-east-1",
-		"us-gov-east-1",
-		"us-gov-west-1",
-	}, nil
+// Column describes a table column
+type Column struct {
+	Name string
+	// Type is the Redshift type name, e.g. "super" or "numeric"
+	Type      string
+	Nullable  bool
+	Precision int64
+	Scale     int64
+	// Ordinal is the position of the column in the table definition, starting at 1
+	Ordinal int
+	// Label is the display name of the column, its name if it has none
+	Label string
 }
 
-func (c *API) Databases(ctx aws.Context, options sqlds.Options) ([]string, error) {
-	commonInput := c.apiInput()
-	input := &redshiftdataapiservice.ListDatabasesInput{
-		ClusterIdentifier: commonInput.ClusterIdentifier,
-		Database:          commonInput.Database,
-		DbUser:            commonInput.DbUser,
-		SecretArn:         commonInput.SecretARN,
+// IsSuper returns true for the SUPER columns, which hold semi-structured data as JSON
+func (c Column) IsSuper() bool {
+	return strings.EqualFold(c.Type, "super")
+}
+
+// ColumnsWithTypes returns the columns of a table with their type, in the order of the table definition
+func (c *API) ColumnsWithTypes(ctx aws.Context, options sqlds.Options) ([]Column, error) {
+	columns, err := c.describeTable(ctx, options)
+	if err != nil {
+		return nil, err
 	}
-	isFinished := false
-	res := []string{}
-	for !isFinished {
-		out, err := c.DataClient.ListDatabasesWithContext(ctx, input)
-		if err != nil {
-			return nil, err
+	res := []Column{}
+	for _, col := range columns {
+		if col.Name == nil {
+			continue
 		}
-		input.NextToken = out.NextToken
-		for _, sc := range out.Databases {
-			if sc != nil {
-				res = append(res, *sc)
-			}
-		}
-		if input.NextToken == nil {
-			isFinished = true
+		label := aws.StringValue(col.Label)
+		if label == "" {
+			label = *col.Name
 		}
+		res = append(res, Column{
+			Name:      *col.Name,
+			Type:      aws.StringValue(col.TypeName),
+			Nullable:  aws.Int64Value(col.Nullable) != 0,
+			Precision: aws.Int64Value(col.Precision),
+			Scale:     aws.Int64Value(col.Scale),
+			Ordinal:   len(res) + 1,
+			Label:     label,
+		})
 	}
 	return res, nil
 }
 
-func (c *API) Schemas(ctx aws.Context, options sqlds.Options) ([]string, error) {
-	commonInput := c.apiInput()
-	input := &redshiftdataapiservice.ListSchemasInput{
-		ClusterIdentifier: commonInput.ClusterIdentifier,
-		Database:          commonInput.Database,
-		DbUser:            commonInput.DbUser,
-		SecretArn:         commonInput.SecretARN,
+const schemaColumnsQuery = `SELECT table_name, column_name, data_type, is_nullable, numeric_precision, numeric_scale
+FROM svv_columns WHERE table_schema = :schema ORDER BY table_name, ordinal_position`
+
+// SchemaColumns returns the columns of every table of the schema by table name. They are read from
+// the svv_columns view with a single statement, if it's not accessible each table is described instead.
+func (c *API) SchemaColumns(ctx aws.Context, schema string) (map[string][]Column, error) {
+	schema = identifierName(schema)
+	res, err := c.querySchemaColumns(ctx, schema)
+	if err == nil {
+		return res, nil
 	}
-	isFinished := false
-	res := []string{}
-	for !isFinished {
-		out, err := c.DataClient.ListSchemasWithContext(ctx, input)
+	if isCanceled(ctx, err) {
+		return nil, fmt.Errorf("%w: %v", CanceledError, err)
+	}
+	c.logger().Debug("unable to query svv_columns, describing each table instead", "schema", schema, "error", err.Error())
+
+	tables, err := c.Tables(ctx, sqlds.Options{"schema": schema})
+	if err != nil {
+		return nil, err
+	}
+	res = map[string][]Column{}
+	for _, table := range tables {
+		columns, err := c.ColumnsWithTypes(ctx, sqlds.Options{"schema": schema, "table": table})
 		if err != nil {
 			return nil, err
 		}
-		input.NextToken = out.NextToken
-		for _, sc := range out.Schemas {
-			if sc != nil {
-				res = append(res, *sc)
-			}
-		}
-		if input.NextToken == nil {
-			isFinished = true
+		res[table] = columns
+	}
+	return res, nil
+}
+
+// catalogQuery runs a query on the system catalog and returns its records
+func (c *API) catalogQuery(ctx aws.Context, query string, parameters map[string]string) ([][]*redshiftdataapiservice.Field, error) {
+	result, err := c.ExecuteAndWait(ctx, &StatementInput{
+		ExecuteQueryInput: api.ExecuteQueryInput{Query: query},
+		Parameters:        parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Records, nil
+}
+
+func (c *API) querySchemaColumns(ctx aws.Context, schema string) (map[string][]Column, error) {
+	records, err := c.catalogQuery(ctx, schemaColumnsQuery, map[string]string{"schema": schema})
+	if err != nil {
+		return nil, err
+	}
+	res := map[string][]Column{}
+	for _, record := range records {
+		if len(record) < 6 || record[0] == nil || record[1] == nil {
+			continue
 		}
+		table, name := aws.StringValue(record[0].StringValue), aws.StringValue(record[1].StringValue)
+		// The columns are sorted by their position in the table
+		res[table] = append(res[table], Column{
+			Name:      name,
+			Type:      aws.StringValue(record[2].StringValue),
+			Nullable:  aws.StringValue(record[3].StringValue) == "YES",
+			Precision: aws.Int64Value(record[4].LongValue),
+			Scale:     aws.Int64Value(record[5].LongValue),
+			Ordinal:   len(res[table]) + 1,
+			Label:     name,
+		})
 	}
 	return res, nil
 }
 
-func (c *API) Tables(ctx aws.Context, options sqlds.Options) ([]string, error) {
-	schema := options["schema"]
-	// We use the "public" schema by default if not specified
-	if schema == "" {
-		schema = "public"
+const primaryKeyQuery = `SELECT kcu.column_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+ON tc.constraint_schema = kcu.constraint_schema AND tc.constraint_name = kcu.constraint_name
+WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = :schema AND tc.table_name = :table`
+
+// TableSchema describes the columns of a table, flagging the ones of its primary key
+type TableSchema struct {
+	Schema  string
+	Table   string
+	Columns []Column
+	// PrimaryKey are the names of the primary key columns, empty if the table has none or they couldn't be read
+	PrimaryKey []string
+}
+
+// IsPrimaryKey returns true if the column is part of the primary key of the table
+func (t *TableSchema) IsPrimaryKey(column string) bool {
+	for _, pk := range t.PrimaryKey {
+		if pk == column {
+			return true
+		}
 	}
-	commonInput := c.apiInput()
-	input := &redshiftdataapiservice.ListTablesInput{
-		ClusterIdentifier: commonInput.ClusterIdentifier,
-		Database:          commonInput.Database,
-		DbUser:            commonInput.DbUser,
-		SecretArn:         commonInput.SecretARN,
-		SchemaPattern:     aws.String(schema),
+	return false
+}
+
+// DescribeTableSchema returns the columns of a table with their type and nullability, and its primary key.
+// The primary key is read from the system catalog, if it's not accessible the table is returned without it.
+func (c *API) DescribeTableSchema(ctx aws.Context, options sqlds.Options) (*TableSchema, error) {
+	columns, err := c.ColumnsWithTypes(ctx, options)
+	if err != nil {
+		return nil, err
 	}
-	isFinished := false
-	res := []string{}
-	for !isFinished {
-		out, err := c.DataClient.ListTablesWithContext(ctx, input)
+	res := &TableSchema{
+		Schema:     identifierName(options["schema"]),
+		Table:      identifierName(options["table"]),
+		Columns:    columns,
+		PrimaryKey: []string{},
+	}
+	records, err := c.catalogQuery(ctx, primaryKeyQuery, map[string]string{"schema": res.Schema, "table": res.Table})
+	if err != nil {
+		if isCanceled(ctx, err) {
+			return nil, fmt.Errorf("%w: %v", CanceledError, err)
+		}
+		c.logger().Warn("unable to read the primary key", "schema", res.Schema, "table", res.Table, "error", err.Error())
+		return res, nil
+	}
+	for _, record := range records {
+		if len(record) > 0 && record[0] != nil && record[0].StringValue != nil {
+			res.PrimaryKey = append(res.PrimaryKey, *record[0].StringValue)
+		}
+	}
+	return res, nil
+}
+
+func (c *API) describeTable(ctx aws.Context, options sqlds.Options) ([]*redshiftdataapiservice.ColumnMetadata, error) {
+	res := []*redshiftdataapiservice.ColumnMetadata{}
+	page := PageInput{}
+	for pages := 1; ; pages++ {
+		out, err := c.describeTablePage(ctx, options, page)
 		if err != nil {
 			return nil, err
 		}
-		input.NextToken = out.NextToken
-		for _, t := range out.Tables {
-			if t.Name != nil {
-				res = append(res, *t.Name)
+		for _, col := range out.ColumnList {
+			if col != nil {
+				res = append(res, col)
 			}
 		}
-		if input.NextToken == nil {
-			isFinished = true
+		if out.NextToken == nil {
+			break
 		}
+		if pages >= c.maxPages() {
+			return res, c.pageLimitError("DescribeTable", pages)
+		}
+		page.NextToken = *out.NextToken
 	}
 	return res, nil
 }
 
-func (c *API) Columns(ctx aws.Context, options sqlds.Options) ([]string, error) {
-	schema, table := options["schema"], options["table"]
-	commonInput := c.apiInput()
+func (c *API) describeTablePage(ctx aws.Context, options sqlds.Options, page PageInput) (*redshiftdataapiservice.DescribeTableOutput, error) {
+	schema, table := identifierName(options["schema"]), identifierName(options["table"])
+	commonInput := c.apiInput(ctx)
+	database, connectedDatabase := catalogDatabases(options, commonInput.Database)
 	input := &redshiftdataapiservice.DescribeTableInput{
 		ClusterIdentifier: commonInput.ClusterIdentifier,
-		Database:          commonInput.Database,
+		WorkgroupName:     commonInput.WorkgroupName,
+		Database:          database,
+		ConnectedDatabase: connectedDatabase,
 		DbUser:            commonInput.DbUser,
 		SecretArn:         commonInput.SecretARN,
 		Schema:            aws.String(schema),
 		Table:             aws.String(table),
 	}
-	isFinished := false
-	res := []string{}
-	for !isFinished {
-		out, err := c.DataClient.DescribeTableWithContext(ctx, input)
-		if err != nil {
-			return nil, err
-		}
-		input.NextToken = out.NextToken
-		for _, c := range out.ColumnList {
-			if c.Name != nil {
-				res = append(res, *c.Name)
-			}
-		}
-		if input.NextToken == nil {
-			isFinished = true
+	input.NextToken, input.MaxResults = pageInput(page)
+	var out *redshiftdataapiservice.DescribeTableOutput
+	err := c.withRetry(ctx, "DescribeTable", func(ctx aws.Context) (err error) {
+		out, err = c.DataClient.DescribeTableWithContext(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, c.clusterNotFoundError(ctx, err, listError(ctx, DescribeTableError, err))
+	}
+	columns := []*redshiftdataapiservice.ColumnMetadata{}
+	for _, col := range out.ColumnList {
+		if col != nil && col.Name != nil {
+			columns = append(columns, col)
 		}
 	}
-	return res, nil
+	if err := c.skippedEntries("DescribeTable", len(out.ColumnList)-len(columns)); err != nil {
+		return nil, err
+	}
+	out.ColumnList = columns
+	return out, nil
 }
 
 func (c *API) Secrets(ctx aws.Context) ([]models.ManagedSecret, error) {
+	if c.SecretsClient == nil {
+		return nil, ManagedSecretsDisabledError
+	}
+	tagKey := c.settings.SecretTagKey
+	if tagKey == "" {
+		// By default only secrets with the tag RedshiftQueryOwner are listed, as the query editor does
+		// https://docs.aws.amazon.com/redshift/latest/mgmt/query-editor.html#query-cluster-configure
+		tagKey = defaultSecretTagKey
+	}
 	input := &secretsmanager.ListSecretsInput{
 		Filters: []*secretsmanager.Filter{
 			{
-				// Only secrets with the tag RedshiftQueryOwner can be used
-				// https://docs.aws.amazon.com/redshift/latest/mgmt/query-editor.html#query-cluster-configure
 				Key:    aws.String(secretsmanager.FilterNameStringTypeTagKey),
-				Values: []*string{aws.String("RedshiftQueryOwner")},
+				Values: []*string{aws.String(tagKey)},
 			},
 		},
 	}
+	if c.settings.SecretTagValue != "" {
+		input.Filters = append(input.Filters, &secretsmanager.Filter{
+			Key:    aws.String(secretsmanager.FilterNameStringTypeTagValue),
+			Values: []*string{aws.String(c.settings.SecretTagValue)},
+		})
+	}
+	redshiftSecrets, err := c.listSecrets(ctx, input)
+	if err != nil || len(redshiftSecrets) > 0 {
+		return redshiftSecrets, err
+	}
+	if !c.settings.ListUntaggedSecrets {
+		c.logger().Info("no managed secret found, the secrets need a tag to be listed", "tag key", tagKey, "tag value", c.settings.SecretTagValue)
+		return redshiftSecrets, nil
+	}
+	// The secret was likely not tagged, so every secret is listed instead
+	untagged, err := c.listSecrets(ctx, &secretsmanager.ListSecretsInput{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range untagged {
+		untagged[i].Untagged = true
+	}
+	return untagged, nil
+}
+
+func (c *API) listSecrets(ctx aws.Context, input *secretsmanager.ListSecretsInput) ([]models.ManagedSecret, error) {
 	isFinished := false
 	redshiftSecrets := []models.ManagedSecret{}
-	for !isFinished {
-		out, err := c.SecretsClient.ListSecretsWithContext(ctx, input)
+	for pages := 1; !isFinished; pages++ {
+		var out *secretsmanager.ListSecretsOutput
+		err := c.withRetry(ctx, "ListSecrets", func(ctx aws.Context) (err error) {
+			var delay time.Duration
+			out, err = c.SecretsClient.ListSecretsWithContext(ctx, input, retryAfter(&delay))
+			return withRetryAfter(err, delay)
+		})
 		if err != nil {
-			return nil, err
+			return nil, listError(ctx, ListSecretsError, err)
 		}
 		input.NextToken = out.NextToken
-		if input.NextToken == nil {
-			isFinished = true
-		}
+		skipped := 0
 		for _, s := range out.SecretList {
-			if s.ARN == nil || s.Name == nil {
+			if s == nil || s.ARN == nil || s.Name == nil {
+				skipped++
 				continue
 			}
 			redshiftSecrets = append(redshiftSecrets, models.ManagedSecret{
@@ -318,27 +1761,73 @@ func (c *API) Secrets(ctx aws.Context) ([]models.ManagedSecret, error) {
 				Name: *s.Name,
 			})
 		}
+		if err := c.skippedEntries("ListSecrets", skipped); err != nil {
+			return nil, err
+		}
+		if input.NextToken == nil {
+			isFinished = true
+		} else if pages >= c.maxPages() {
+			return redshiftSecrets, c.pageLimitError("ListSecrets", pages)
+		}
 	}
 	return redshiftSecrets, nil
 }
 
 func (c *API) Secret(ctx aws.Context, options sqlds.Options) (*models.RedshiftSecret, error) {
+	if c.SecretsClient == nil {
+		return nil, ManagedSecretsDisabledError
+	}
 	arn := options["secretARN"]
+	if res, ok := c.secrets.get(arn); ok {
+		return res, nil
+	}
 	input := &secretsmanager.GetSecretValueInput{
 		SecretId: aws.String(arn),
 	}
-	out, err := c.SecretsClient.GetSecretValueWithContext(ctx, input)
+	var out *secretsmanager.GetSecretValueOutput
+	err := c.withRetry(ctx, "GetSecretValue", func(ctx aws.Context) (err error) {
+		var delay time.Duration
+		out, err = c.SecretsClient.GetSecretValueWithContext(ctx, input, retryAfter(&delay))
+		return withRetryAfter(err, delay)
+	})
 	if err != nil {
-		return nil, err
+		return nil, secretValueError(err)
 	}
 	if out == nil {
 		return nil, fmt.Errorf("missing secret content")
 	}
-	res := &models.RedshiftSecret{}
-	err = json.Unmarshal([]byte(*out.SecretString), res)
+	res, err := parseSecret(arn, out)
 	if err != nil {
 		return nil, err
 	}
+	if rotated := c.secrets.set(arn, aws.StringValue(out.VersionId), res); rotated {
+		c.logger().Debug("managed secret has been rotated", "arn", arn)
+	}
+	return res, nil
+}
+
+// parseSecret reads the secret content, stored either as a string or as binary data
+func parseSecret(arn string, out *secretsmanager.GetSecretValueOutput) (*models.RedshiftSecret, error) {
+	var content []byte
+	switch {
+	case out.SecretString != nil:
+		content = []byte(*out.SecretString)
+	case out.SecretBinary != nil:
+		// The SDK decodes the binary payload, which may still be base64 if it was stored encoded
+		content = out.SecretBinary
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content))); err == nil && json.Valid(decoded) {
+			content = decoded
+		}
+	default:
+		return nil, fmt.Errorf("%w %s: the secret has no value", InvalidSecretError, arn)
+	}
+	res := &models.RedshiftSecret{}
+	if err := json.Unmarshal(content, res); err != nil {
+		return nil, fmt.Errorf(`%w %s: expected a JSON object with "username" and "dbClusterIdentifier" keys: %v`, InvalidSecretError, arn, err)
+	}
+	if res.DBUser == "" {
+		return nil, fmt.Errorf(`%w %s: missing "username" key`, InvalidSecretError, arn)
+	}
 	return res, nil
 }
 
@@ -352,7 +1841,7 @@ func (c *API) Clusters() ([]models.RedshiftCluster, error) {
 	}
 	res := []models.RedshiftCluster{}
 	for _, r := range out.Clusters {
-		if (r != nil && r.ClusterIdentifier != nil && r.Endpoint != nil && r.Endpoint.Address != nil && r.Endpoint.Port != nil && r.DBName != nil) {
+		if r != nil && r.ClusterIdentifier != nil && r.Endpoint != nil && r.Endpoint.Address != nil && r.Endpoint.Port != nil && r.DBName != nil {
 			res = append(res, models.RedshiftCluster{
 				ClusterIdentifier: *r.ClusterIdentifier,
 				Endpoint: models.RedshiftEndpoint{
@@ -2,13 +2,28 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/redshift"
 	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/redshiftserverless"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 	"github.com/grafana/grafana-aws-sdk/pkg/sql/api"
+	"github.com/grafana/redshift-datasource/pkg/redshift/api/internal/fakeredshift"
 	redshiftclientmock "github.com/grafana/redshift-datasource/pkg/redshift/api/mock"
 	"github.com/grafana/redshift-datasource/pkg/redshift/models"
 	"github.com/grafana/sqlds/v2"
@@ -51,11 +66,38 @@ func Test_apiInput(t *testing.T) {
 				SecretARN:         aws.String("arn:..."),
 			},
 		},
+		{
+			"using a serverless workgroup",
+			&models.RedshiftDataSourceSettings{
+				WorkgroupName: "workgroup",
+				Database:      "db",
+			},
+			apiInput{
+				WorkgroupName: aws.String("workgroup"),
+				Database:      aws.String("db"),
+			},
+		},
+		{
+			"using a serverless workgroup with a managed secret",
+			&models.RedshiftDataSourceSettings{
+				UseManagedSecret: true,
+				WorkgroupName:    "workgroup",
+				Database:         "db",
+				ManagedSecret:    models.ManagedSecret{ARN: "arn:..."},
+				// ignored
+				DBUser: "user",
+			},
+			apiInput{
+				WorkgroupName: aws.String("workgroup"),
+				Database:      aws.String("db"),
+				SecretARN:     aws.String("arn:..."),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.description, func(t *testing.T) {
 			api := &API{settings: tt.settings}
-			res := api.apiInput()
+			res := api.apiInput(context.Background())
 			if !cmp.Equal(res, tt.expected) {
 				t.Errorf("unexpected result: %v", cmp.Diff(res, tt.expected))
 			}
@@ -63,6 +105,132 @@ func Test_apiInput(t *testing.T) {
 	}
 }
 
+// testSettings returns valid settings for the tests running statements through ExecuteAndWait or HealthCheck
+func testSettings() *models.RedshiftDataSourceSettings {
+	return &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"}
+}
+
+func Test_New(t *testing.T) {
+	tests := []struct {
+		description string
+		settings    *models.RedshiftDataSourceSettings
+		err         string
+	}{
+		{
+			"cluster identifier and workgroup are mutually exclusive",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", WorkgroupName: "workgroup", Database: "db", DBUser: "user"},
+			"cluster identifier and workgroup name are mutually exclusive, only one of them can be set",
+		},
+		{
+			"missing cluster identifier and workgroup",
+			&models.RedshiftDataSourceSettings{Database: "db", DBUser: "user"},
+			"missing cluster identifier or workgroup name",
+		},
+		{
+			"missing database",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", DBUser: "user"},
+			"missing database",
+		},
+		{
+			"missing managed secret",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", UseManagedSecret: true, DBUser: "user"},
+			"missing managed secret, select one or use temporary credentials instead",
+		},
+		{
+			"managed secret with managed secrets disabled",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}, DisableManagedSecrets: true},
+			"managed secrets not enabled, a managed secret can't be used",
+		},
+		{
+			"invalid timestamp time zone",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user", TimestampTimeZone: "Mars/Olympus"},
+			`invalid timestamp time zone "Mars/Olympus": unknown time zone Mars/Olympus`,
+		},
+		{
+			"missing database user",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"},
+			"missing database user, it's required when using temporary credentials with a cluster",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			// The API is created with incomplete settings, the statements are rejected
+			res, err := New(awsds.NewSessionCache(), tt.settings)
+			assert.NoError(t, err)
+			c := res.(*API)
+			c.DataClient = &redshiftclientmock.MockRedshiftClient{}
+			assert.EqualError(t, c.CheckSettings(context.TODO()), tt.err)
+			_, err = c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}})
+			assert.EqualError(t, err, tt.err)
+
+			var healthErr *HealthCheckError
+			assert.ErrorAs(t, c.HealthCheck(context.TODO()), &healthErr)
+			assert.Equal(t, HealthCheckSettingsInvalid, healthErr.Stage)
+			assert.EqualError(t, healthErr, "health check failed, invalid settings: "+tt.err)
+		})
+	}
+}
+
+func Test_NewLoader(t *testing.T) {
+	httpClient := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: "proxy:3128"})}}
+	settings := &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"}
+	res, err := NewLoader(httpClient)(awsds.NewSessionCache(), settings)
+	assert.NoError(t, err)
+	c := res.(*API)
+	assert.Same(t, httpClient, c.DataClient.(*redshiftdataapiservice.RedshiftDataAPIService).Config.HTTPClient)
+	assert.Same(t, httpClient, c.SecretsClient.(*secretsmanager.SecretsManager).Config.HTTPClient)
+
+	_, err = NewLoader(httpClient)(awsds.NewSessionCache(), &models.RedshiftDataSourceSettings{})
+	assert.NoError(t, err)
+}
+
+func Test_NewSecretsRegion(t *testing.T) {
+	settings := &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user", SecretsRegion: "eu-west-1"}
+	settings.Region = "us-east-1"
+	res, err := New(awsds.NewSessionCache(), settings)
+	assert.NoError(t, err)
+	c := res.(*API)
+	secrets := c.SecretsClient.(*secretsmanager.SecretsManager)
+	assert.Equal(t, "eu-west-1", aws.StringValue(secrets.Config.Region))
+	assert.Equal(t, "https://secretsmanager.eu-west-1.amazonaws.com", secrets.Endpoint)
+	data := c.DataClient.(*redshiftdataapiservice.RedshiftDataAPIService)
+	assert.Equal(t, "us-east-1", aws.StringValue(data.Config.Region))
+}
+
+func Test_NewRedshiftAPI(t *testing.T) {
+	res, err := NewRedshiftAPI(awsds.NewSessionCache(), &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"})
+	assert.NoError(t, err)
+	assert.IsType(t, &API{}, res)
+
+	_, err = NewRedshiftAPI(awsds.NewSessionCache(), &models.RedshiftDataSourceSettings{})
+	assert.NoError(t, err)
+}
+
+func Test_validateSettings(t *testing.T) {
+	tests := []struct {
+		description string
+		settings    *models.RedshiftDataSourceSettings
+	}{
+		{
+			"temporary credentials",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"},
+		},
+		{
+			"managed secret",
+			&models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}},
+		},
+		{
+			"serverless without database user",
+			&models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			assert.NoError(t, validateSettings(tt.settings))
+		})
+	}
+}
+
 func Test_Execute(t *testing.T) {
 	c := &API{
 		settings:   &models.RedshiftDataSourceSettings{},
@@ -78,6 +246,150 @@ func Test_Execute(t *testing.T) {
 	}
 }
 
+func Test_ExecuteStatement(t *testing.T) {
+	t.Run("binds named parameters", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		_, err := c.ExecuteStatement(context.TODO(), &StatementInput{
+			ExecuteQueryInput: api.ExecuteQueryInput{Query: "select * from foo where a = :a and b = :b"},
+			Parameters:        map[string]string{"b": "2", "a": "1"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []*redshiftdataapiservice.SqlParameter{
+			{Name: aws.String("a"), Value: aws.String("1")},
+			{Name: aws.String("b"), Value: aws.String("2")},
+		}, client.ExecuteStatementInput.Parameters)
+	})
+
+	t.Run("omits empty parameters", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		_, err := c.ExecuteStatement(context.TODO(), &StatementInput{
+			ExecuteQueryInput: api.ExecuteQueryInput{Query: "select * from foo"},
+			Parameters:        map[string]string{},
+		})
+		assert.NoError(t, err)
+		assert.Nil(t, client.ExecuteStatementInput.Parameters)
+	})
+}
+
+func Test_ExecuteStatementName(t *testing.T) {
+	tests := []struct {
+		description string
+		ctx         context.Context
+		name        string
+		expected    *string
+	}{
+		{"no name", context.TODO(), "", nil},
+		{"given name", ContextWithPanel(context.TODO(), "dash", 2), "my-query", aws.String("my-query")},
+		{"panel name", ContextWithPanel(context.TODO(), "dash", 2), "", aws.String("grafana-dash-2")},
+		{"truncated name", context.TODO(), strings.Repeat("a", 600), aws.String(strings.Repeat("a", 500))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+			_, err := c.ExecuteStatement(tt.ctx, &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}, StatementName: tt.name})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, client.ExecuteStatementInput.StatementName)
+		})
+	}
+}
+
+func Test_ExecuteWithEvent(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+	c := &API{settings: &models.RedshiftDataSourceSettings{WithEvent: true}, DataClient: client}
+	_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+	assert.NoError(t, err)
+	assert.Equal(t, aws.Bool(true), client.ExecuteStatementInput.WithEvent)
+}
+
+func Test_ExecuteDatabase(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{
+		ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+		Resources:       map[string]map[string][]string{"public": {}},
+	}
+	c := &API{settings: &models.RedshiftDataSourceSettings{Database: "default"}, DataClient: client}
+
+	_, err := c.ExecuteStatement(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}, Database: "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "other", *client.ExecuteStatementInput.Database)
+
+	_, err = c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "default", *client.ExecuteStatementInput.Database)
+
+	_, err = c.Tables(context.TODO(), sqlds.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "default", *client.ListTablesInput.Database)
+	assert.Equal(t, "default", c.settings.Database)
+}
+
+func Test_BatchExecute(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{
+		ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+		DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+			Id:     aws.String("foo"),
+			Status: aws.String(redshiftdataapiservice.StatusStringFailed),
+			Error:  aws.String("batch failed"),
+			SubStatements: []*redshiftdataapiservice.SubStatementData{
+				{Id: aws.String("foo:1"), Status: aws.String(redshiftdataapiservice.StatementStatusStringFinished)},
+				{Id: aws.String("foo:2"), Status: aws.String(redshiftdataapiservice.StatementStatusStringFailed), Error: aws.String("syntax error")},
+			},
+		},
+	}
+	c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"}, DataClient: client}
+	output, err := c.BatchExecute(context.TODO(), []string{"create table foo (id int)", "insert into foo valus (1)"})
+	assert.NoError(t, err)
+	assert.Equal(t, &api.ExecuteQueryOutput{ID: "foo"}, output)
+	assert.Equal(t, aws.StringSlice([]string{"create table foo (id int)", "insert into foo valus (1)"}), client.BatchExecuteStatementInput.Sqls)
+	assert.Equal(t, aws.String("cluster"), client.BatchExecuteStatementInput.ClusterIdentifier)
+
+	status, err := c.StatementStatus(context.TODO(), output)
+	assert.EqualError(t, err, "statement 2 failed: syntax error")
+	assert.Equal(t, 1, status.FailedSubStatement)
+}
+
+func Test_ExecuteRetries(t *testing.T) {
+	t.Run("retries throttled calls", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			ThrottledCalls:  2,
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, DataClient: client}
+		res, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select * from foo"})
+		assert.NoError(t, err)
+		assert.Equal(t, &api.ExecuteQueryOutput{ID: "foo"}, res)
+		assert.Equal(t, 3, client.Calls)
+	})
+
+	t.Run("gives up after the configured retries", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ThrottledCalls: 5}
+		c := &API{settings: &models.RedshiftDataSourceSettings{MaxRetries: 2, RetryDelay: 1}, DataClient: client}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select * from foo"})
+		assert.ErrorIs(t, err, api.ExecuteError)
+		assert.Equal(t, 3, client.Calls)
+	})
+
+	t.Run("doesn't retry when the retries are disabled", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ThrottledCalls: 5}
+		c := &API{settings: &models.RedshiftDataSourceSettings{MaxRetries: -1, RetryDelay: 1}, DataClient: client}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select * from foo"})
+		assert.ErrorIs(t, err, api.ExecuteError)
+		assert.Equal(t, 1, client.Calls)
+	})
+
+	t.Run("stops retrying when the context is done", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ThrottledCalls: 5}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1000}, DataClient: client}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := c.Execute(ctx, &api.ExecuteQueryInput{Query: "select * from foo"})
+		assert.ErrorIs(t, err, CanceledError)
+		assert.Equal(t, 1, client.Calls)
+	})
+}
+
 func Test_Status(t *testing.T) {
 	tests := []struct {
 		description string
@@ -125,151 +437,3291 @@ func Test_Status(t *testing.T) {
 	}
 }
 
-func Test_ListSchemas(t *testing.T) {
-	resources := map[string]map[string][]string{
-		"foo": {},
-		"bar": {},
-	}
-	expectedResult := []string{"bar", "foo"}
-	c := &API{
-		settings:   &models.RedshiftDataSourceSettings{},
-		DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
-	}
-	res, err := c.Schemas(context.TODO(), sqlds.Options{})
-	if err != nil {
-		t.Fatalf("unexpected error %v", err)
-	}
-	sort.Strings(res)
-	if !cmp.Equal(expectedResult, res) {
-		t.Errorf("unexpected result: %v", cmp.Diff(expectedResult, res))
-	}
+func Test_ExecuteClusterPaused(t *testing.T) {
+	t.Run("paused cluster", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecuteError: awserr.New("ValidationException", "Cluster foo is paused", nil),
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.ErrorIs(t, err, ClusterPausedError)
+		assert.ErrorIs(t, err, api.ExecuteError)
+	})
+
+	t.Run("retries while the cluster is resuming", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecuteErrors:   []error{awserr.New("ValidationException", "Cluster foo is resuming", nil)},
+			ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, DataClient: client}
+		res, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", res.ID)
+	})
+
+	t.Run("other errors mentioning a paused or resuming state", func(t *testing.T) {
+		for _, executeErr := range []error{
+			awserr.New("ValidationException", "column \"paused\" does not exist", nil),
+			awserr.New("ValidationException", "Workgroup foo is resuming", nil),
+			awserr.New("InternalServerException", "Cluster foo is paused", nil),
+			errors.New("cluster foo is paused"),
+		} {
+			client := &redshiftclientmock.MockRedshiftClient{ExecuteError: executeErr}
+			c := &API{settings: &models.RedshiftDataSourceSettings{MaxRetries: -1}, DataClient: client}
+			_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+			assert.ErrorIs(t, err, api.ExecuteError)
+			assert.NotErrorIs(t, err, ClusterPausedError, executeErr.Error())
+			assert.Equal(t, 1, client.Calls, executeErr.Error())
+		}
+	})
 }
 
-func Test_ListTables(t *testing.T) {
-	resources := map[string]map[string][]string{
-		"foo": {
-			"foofoo": {},
-		},
-		"bar": {
-			"barbar": {},
-		},
-	}
-	expectedResult := []string{"foofoo"}
+func Test_StatementStatus(t *testing.T) {
 	c := &API{
-		settings:   &models.RedshiftDataSourceSettings{},
-		DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
-	}
-	res, err := c.Tables(context.TODO(), sqlds.Options{"schema": "foo"})
-	if err != nil {
-		t.Fatalf("unexpected error %v", err)
-	}
-	if !cmp.Equal(expectedResult, res) {
-		t.Errorf("unexpected result: %v", cmp.Diff(expectedResult, res))
+		settings: &models.RedshiftDataSourceSettings{},
+		DataClient: &redshiftclientmock.MockRedshiftClient{
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+				Id:     aws.String("foo"),
+				Status: aws.String(redshiftdataapiservice.StatusStringFailed),
+				Error:  aws.String("parent error"),
+				SubStatements: []*redshiftdataapiservice.SubStatementData{
+					{Id: aws.String("foo:1"), Status: aws.String(redshiftdataapiservice.StatementStatusStringFinished)},
+					{Id: aws.String("foo:2"), Status: aws.String(redshiftdataapiservice.StatementStatusStringFailed), Error: aws.String("syntax error")},
+					{Id: aws.String("foo:3"), Status: aws.String(redshiftdataapiservice.StatementStatusStringAborted)},
+				},
+			},
+		},
 	}
+	status, err := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+	assert.EqualError(t, err, "statement 2 failed: syntax error")
+	assert.True(t, status.Finished)
+	assert.Equal(t, 1, status.FailedSubStatement)
+	assert.Equal(t, []SubStatementStatus{
+		{ID: "foo:1", State: redshiftdataapiservice.StatementStatusStringFinished},
+		{ID: "foo:2", State: redshiftdataapiservice.StatementStatusStringFailed, Error: "syntax error"},
+		{ID: "foo:3", State: redshiftdataapiservice.StatementStatusStringAborted},
+	}, status.SubStatements)
 }
 
-func Test_ListColumns(t *testing.T) {
-	resources := map[string]map[string][]string{
-		"public": {
-			"foo": {
-				"col1",
-				"col2",
+func Test_StatementStatusStats(t *testing.T) {
+	t.Run("returns the stats of a finished query", func(t *testing.T) {
+		c := &API{
+			settings: &models.RedshiftDataSourceSettings{},
+			DataClient: &redshiftclientmock.MockRedshiftClient{
+				DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+					Id:         aws.String("foo"),
+					Status:     aws.String(redshiftdataapiservice.StatusStringFinished),
+					Duration:   aws.Int64(int64(2 * time.Second)),
+					ResultRows: aws.Int64(10),
+					ResultSize: aws.Int64(256),
+				},
 			},
-			"bar": {
-				"col3",
+		}
+		status, err := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.NoError(t, err)
+		assert.Equal(t, QueryStats{Duration: 2 * time.Second, ResultRows: 10, ResultSize: 256}, status.Stats)
+	})
+
+	t.Run("ignores missing stats of a query in progress", func(t *testing.T) {
+		c := &API{
+			settings: &models.RedshiftDataSourceSettings{},
+			DataClient: &redshiftclientmock.MockRedshiftClient{
+				DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+					Id:     aws.String("foo"),
+					Status: aws.String(redshiftdataapiservice.StatusStringStarted),
+				},
 			},
-		},
-	}
-	expectedResult := []string{"col1", "col2"}
-	c := &API{
-		settings:   &models.RedshiftDataSourceSettings{},
-		DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
-	}
-	res, err := c.Columns(context.TODO(), sqlds.Options{"schema": "public", "table": "foo"})
-	if err != nil {
-		t.Fatalf("unexpected error %v", err)
-	}
-	if !cmp.Equal(expectedResult, res) {
-		t.Errorf("unexpected result: %v", cmp.Diff(expectedResult, res))
-	}
+		}
+		status, err := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.NoError(t, err)
+		assert.Equal(t, QueryStats{}, status.Stats)
+	})
 }
-func Test_ListSecrets(t *testing.T) {
-	expectedSecrets := []models.ManagedSecret{{Name: "foo", ARN: "arn:foo"}}
-	c := &API{SecretsClient: &redshiftclientmock.MockRedshiftClient{Secrets: []string{"foo"}}}
-	secrets, err := c.Secrets(context.TODO())
-	if err != nil {
-		t.Fatalf("unexpected error %v", err)
+
+func Test_StatusNotFound(t *testing.T) {
+	notFound := awserr.New(redshiftdataapiservice.ErrCodeResourceNotFoundException, "Query does not exist.", nil)
+	defer func(delay time.Duration) { statementNotFoundDelay = delay }(statementNotFoundDelay)
+	statementNotFoundDelay = time.Millisecond
+
+	t.Run("retries right after submit", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			DescribeStatementErrors: []error{notFound},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished)},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		status, err := c.Status(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.NoError(t, err)
+		assert.True(t, status.Finished)
+	})
+
+	t.Run("reports an unknown statement after the retries", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			DescribeStatementErrors: []error{notFound, notFound, notFound, notFound},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished)},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		_, err := c.Status(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.ErrorIs(t, err, StatementNotFoundError)
+		assert.ErrorIs(t, err, api.StatusError)
+	})
+}
+
+func Test_StatementPhase(t *testing.T) {
+	tests := []struct {
+		state    string
+		phase    Phase
+		finished bool
+	}{
+		{redshiftdataapiservice.StatusStringSubmitted, PhaseQueued, false},
+		{redshiftdataapiservice.StatusStringPicked, PhaseQueued, false},
+		{redshiftdataapiservice.StatusStringStarted, PhaseRunning, false},
+		{redshiftdataapiservice.StatusStringFinished, PhaseFinished, true},
+		{redshiftdataapiservice.StatusStringAborted, PhaseFailed, true},
 	}
-	if !cmp.Equal(expectedSecrets, secrets) {
-		t.Errorf("unexpected result: %v", cmp.Diff(expectedSecrets, secrets))
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			c := &API{DataClient: &redshiftclientmock.MockRedshiftClient{
+				DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(tt.state), Error: aws.String("")},
+			}}
+			status, _ := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+			assert.Equal(t, tt.phase, status.Phase)
+			assert.Equal(t, tt.finished, status.Finished)
+		})
 	}
 }
 
-func Test_GetSecret(t *testing.T) {
-	secretContent := `{"dbClusterIdentifier":"foo","username":"bar"}`
-	c := &API{SecretsClient: &redshiftclientmock.MockRedshiftClient{Secret: secretContent}}
-	secret, err := c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
-	if err != nil {
-		t.Fatalf("unexpected error %v", err)
+func Test_WaitOnQuery(t *testing.T) {
+	t.Run("polls until the statement finishes", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			Statuses: []string{redshiftdataapiservice.StatusStringSubmitted, redshiftdataapiservice.StatusStringStarted},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+				Id:     aws.String("foo"),
+				Status: aws.String(redshiftdataapiservice.StatusStringFinished),
+			},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		status, err := c.WaitOnQuery(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"}, time.Millisecond)
+		assert.NoError(t, err)
+		assert.True(t, status.Finished)
+		assert.Equal(t, redshiftdataapiservice.StatusStringFinished, status.State)
+		assert.Equal(t, 3, client.Calls)
+	})
+
+	t.Run("returns the statement error", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+				Id:     aws.String("foo"),
+				Status: aws.String(redshiftdataapiservice.StatusStringFailed),
+				Error:  aws.String("boom"),
+			},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		status, err := c.WaitOnQuery(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"}, time.Millisecond)
+		assert.EqualError(t, err, "boom")
+		assert.True(t, status.Finished)
+	})
+
+	t.Run("stops the statement when canceled", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+				Id:     aws.String("foo"),
+				Status: aws.String(redshiftdataapiservice.StatusStringStarted),
+			},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := c.WaitOnQuery(ctx, &api.ExecuteQueryOutput{ID: "foo"}, time.Minute)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.True(t, client.Canceled)
+	})
+
+	t.Run("stops the statement after the query timeout", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+				Id:     aws.String("foo"),
+				Status: aws.String(redshiftdataapiservice.StatusStringStarted),
+			},
+			CancelError: errors.New("boom"),
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{QueryTimeout: 1}, DataClient: client}
+		_, err := c.WaitOnQuery(context.Background(), &api.ExecuteQueryOutput{ID: "foo"}, time.Minute)
+		assert.ErrorIs(t, err, TimeoutError)
+		assert.EqualError(t, err, "query timed out after 1s")
+		assert.True(t, client.Canceled)
+	})
+}
+
+func Test_HealthCheck(t *testing.T) {
+	describe := func(status string) *redshiftdataapiservice.DescribeStatementOutput {
+		return &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(status), Error: aws.String("boom")}
 	}
-	expectedSecret := &models.RedshiftSecret{ClusterIdentifier: "foo", DBUser: "bar"}
-	if !cmp.Equal(expectedSecret, secret) {
-		t.Errorf("unexpected result: %v", cmp.Diff(expectedSecret, secret))
+	t.Run("succeeds when SELECT 1 finishes", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: describe(redshiftdataapiservice.StatusStringFinished),
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		assert.NoError(t, c.HealthCheck(context.TODO()))
+		assert.Equal(t, "SELECT 1", *client.ExecuteStatementInput.Sql)
+	})
+
+	t.Run("describes an authentication failure", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecuteError: awserr.New("AccessDeniedException", "not allowed", nil)}
+		c := &API{settings: testSettings(), DataClient: client}
+		err := c.HealthCheck(context.TODO())
+		assert.ErrorIs(t, err, api.ExecuteError)
+		assert.Contains(t, err.Error(), "check the credentials")
+	})
+
+	t.Run("describes a password rejected by the database", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecuteError: awserr.New(redshiftdataapiservice.ErrCodeValidationException, "password authentication failed for user \"admin\"", nil)}
+		c := &API{settings: testSettings(), DataClient: client}
+		err := c.HealthCheck(context.TODO())
+		var permissionErr *PermissionError
+		assert.False(t, errors.As(err, &permissionErr))
+		assert.Contains(t, err.Error(), "check the credentials")
+	})
+
+	t.Run("returns the statement error", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: describe(redshiftdataapiservice.StatusStringFailed),
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		assert.EqualError(t, c.HealthCheck(context.TODO()), "health check failed: boom")
+	})
+
+	t.Run("stops the statement on timeout", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: describe(redshiftdataapiservice.StatusStringStarted),
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+		defer cancel()
+		err := c.HealthCheck(ctx)
+		assert.EqualError(t, err, "health check failed: the statement did not finish within 10s")
+		assert.True(t, client.Canceled)
+	})
+}
+
+func Test_Logging(t *testing.T) {
+	logger := &redshiftclientmock.MockLogger{}
+	client := &redshiftclientmock.MockRedshiftClient{
+		ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+		Statuses:                []string{redshiftdataapiservice.StatusStringStarted},
+		DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished)},
 	}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client, Logger: logger}
+	output, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select secret from foo"})
+	assert.NoError(t, err)
+	_, err = c.WaitOnQuery(context.TODO(), output, time.Millisecond)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"statement submitted", "statement status changed", "statement status changed", "statement finished"}, logger.Messages)
+	assert.Contains(t, logger.Args, "foo")
+	assert.NotContains(t, fmt.Sprint(logger.Args...), "select secret from foo")
 }
 
-func Test_GetClusters(t *testing.T) {
-	c := &API{ManagementClient: &redshiftclientmock.MockRedshiftClient{Clusters: []string{"foo", "bar"}}}
-	errC := &API{ManagementClient: &redshiftclientmock.MockRedshiftClientError{}}
-	nilC := &API{ManagementClient: &redshiftclientmock.MockRedshiftClientNil{}}
-	expectedCluster1 := &models.RedshiftCluster{
-		ClusterIdentifier: "foo",
-		Endpoint: models.RedshiftEndpoint{
-			Address: "foo",
-			Port:    123,
+type testMetrics struct {
+	calls     map[string]int
+	throttles map[string]int
+	queries   []QueryStats
+}
+
+func (m *testMetrics) IncCall(operation string)              { m.calls[operation]++ }
+func (m *testMetrics) IncThrottle(operation string)          { m.throttles[operation]++ }
+func (m *testMetrics) ObserveDuration(string, time.Duration) {}
+func (m *testMetrics) ObserveQuery(stats QueryStats)         { m.queries = append(m.queries, stats) }
+
+func Test_Metrics(t *testing.T) {
+	metrics := &testMetrics{calls: map[string]int{}, throttles: map[string]int{}}
+	client := &redshiftclientmock.MockRedshiftClient{
+		ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+		ThrottledCalls:  1,
+		DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{
+			Id:         aws.String("foo"),
+			Status:     aws.String(redshiftdataapiservice.StatusStringFinished),
+			ResultRows: aws.Int64(10),
 		},
-		Database: "foo",
 	}
-	expectedCluster2 := &models.RedshiftCluster{
-		ClusterIdentifier: "bar",
-		Endpoint: models.RedshiftEndpoint{
-			Address: "bar",
-			Port:    123,
-		},
-		Database: "bar",
+	c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, DataClient: client, Metrics: metrics}
+	output, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+	assert.NoError(t, err)
+	_, err = c.WaitOnQuery(context.TODO(), output, time.Millisecond)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Stop(output))
+
+	assert.Equal(t, map[string]int{"ExecuteStatement": 2, "DescribeStatement": 1, "CancelStatement": 1}, metrics.calls)
+	assert.Equal(t, map[string]int{"ExecuteStatement": 1}, metrics.throttles)
+	assert.Equal(t, []QueryStats{{ResultRows: 10}}, metrics.queries)
+}
+
+func Test_Stop(t *testing.T) {
+	t.Run("cancels the statement", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		err := c.StopWithContext(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.NoError(t, err)
+		assert.True(t, client.Canceled)
+	})
+
+	t.Run("wraps the stop error", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{CancelError: fmt.Errorf("boom")}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		err := c.Stop(&api.ExecuteQueryOutput{ID: "foo"})
+		assert.True(t, errors.Is(err, api.StopError))
+		assert.EqualError(t, err, "error stopping query: boom")
+	})
+}
+
+func Test_GetResult(t *testing.T) {
+	columns := []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("col")}}
+	page := func(values ...string) *redshiftdataapiservice.GetStatementResultOutput {
+		res := &redshiftdataapiservice.GetStatementResultOutput{ColumnMetadata: columns}
+		for _, v := range values {
+			res.Records = append(res.Records, []*redshiftdataapiservice.Field{{StringValue: aws.String(v)}})
+		}
+		return res
 	}
+	records := func(values ...string) [][]*redshiftdataapiservice.Field {
+		return page(values...).Records
+	}
+
+	t.Run("concatenates all pages", func(t *testing.T) {
+		c := &API{DataClient: &redshiftclientmock.MockRedshiftClient{Results: []*redshiftdataapiservice.GetStatementResultOutput{page("a", "b"), page("c")}}}
+		res, err := c.GetResult(context.TODO(), "foo", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, columns, res.ColumnMetadata)
+		assert.Equal(t, records("a", "b", "c"), res.Records)
+	})
+
+	t.Run("stops at the max rows", func(t *testing.T) {
+		c := &API{DataClient: &redshiftclientmock.MockRedshiftClient{Results: []*redshiftdataapiservice.GetStatementResultOutput{page("a", "b"), page("c")}}}
+		res, err := c.GetResult(context.TODO(), "foo", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, records("a"), res.Records)
+	})
+
+	t.Run("returns an error for statements without result", func(t *testing.T) {
+		c := &API{DataClient: &redshiftclientmock.MockRedshiftClient{NoResult: true}}
+		_, err := c.GetResult(context.TODO(), "foo", 0)
+		assert.ErrorIs(t, err, NoResultSetError)
+	})
+}
+
+func Test_ExecuteAndWait(t *testing.T) {
+	finished := &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(true)}
+	records := [][]*redshiftdataapiservice.Field{{{LongValue: aws.Int64(1)}}}
+	columns := []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("?column?")}}
+
+	t.Run("returns the result", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: finished,
+			Results:                 []*redshiftdataapiservice.GetStatementResultOutput{{ColumnMetadata: columns, Records: records}},
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		res, err := c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", res.ID)
+		assert.Equal(t, columns, res.Columns)
+		assert.Equal(t, records, res.Records)
+		assert.True(t, res.Status.Finished)
+	})
+
+	t.Run("doesn't fetch the result of statements without one", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(false)},
+			NoResult:                true,
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		res, err := c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "vacuum"}})
+		assert.NoError(t, err)
+		assert.Empty(t, res.Records)
+		assert.False(t, res.Status.HasResultSet)
+		assert.Equal(t, 0, client.ResultCalls)
+	})
+
 	tests := []struct {
-		c                *API
-		desc             string
-		errMsg           string
-		expectedClusters []models.RedshiftCluster
+		description string
+		client      *redshiftclientmock.MockRedshiftClient
+		err         error
 	}{
 		{
-			c:                c,
-			desc:             "Happy Path",
-			expectedClusters: []models.RedshiftCluster{*expectedCluster1, *expectedCluster2},
+			description: "execute fails",
+			client:      &redshiftclientmock.MockRedshiftClient{ExecuteError: errors.New("boom")},
+			err:         api.ExecuteError,
 		},
 		{
-			c:         errC,
-			desc:      "Error with DescribeCluster",
-			errMsg:    "Boom!",
+			description: "status fails",
+			client: &redshiftclientmock.MockRedshiftClient{
+				ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+				DescribeStatementErrors: []error{errors.New("boom")},
+			},
+			err: api.StatusError,
 		},
 		{
-			c:         nilC,
-			desc:      "DescribeCluster returned nil",
-			errMsg:    "missing clusters content",
+			description: "no result",
+			client: &redshiftclientmock.MockRedshiftClient{
+				ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+				DescribeStatementOutput: finished,
+				NoResult:                true,
+			},
+			err: NoResultSetError,
 		},
 	}
 	for _, tt := range tests {
-		t.Run(tt.desc, func(t *testing.T) {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{settings: testSettings(), DataClient: tt.client}
+			_, err := c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}})
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("returns the error of a failed statement", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFailed), Error: aws.String("boom")},
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		_, err := c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}})
+		assert.EqualError(t, err, "boom")
+	})
+}
+
+func Test_ResultIterator(t *testing.T) {
+	columns := []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("col")}}
+	page := func(values ...string) *redshiftdataapiservice.GetStatementResultOutput {
+		res := &redshiftdataapiservice.GetStatementResultOutput{ColumnMetadata: columns}
+		for _, v := range values {
+			res.Records = append(res.Records, []*redshiftdataapiservice.Field{{StringValue: aws.String(v)}})
+		}
+		return res
+	}
+	read := func(it *ResultIterator, max int) []string {
+		values := []string{}
+		for len(values) != max && it.Next(context.TODO()) {
+			values = append(values, *it.Record()[0].StringValue)
+		}
+		return values
+	}
+
+	t.Run("reads every page lazily", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Results: []*redshiftdataapiservice.GetStatementResultOutput{page("a", "b"), page(), page("c")}}
+		c := &API{DataClient: client}
+		it := c.Results("foo")
+		assert.Equal(t, 0, client.ResultCalls)
+		assert.Equal(t, []string{"a"}, read(it, 1))
+		assert.Equal(t, columns, it.Columns())
+		assert.Equal(t, 1, client.ResultCalls)
+		assert.Equal(t, []string{"b", "c"}, read(it, -1))
+		assert.NoError(t, it.Err())
+		assert.False(t, it.Next(context.TODO()))
+		assert.Equal(t, 3, client.ResultCalls)
+	})
+
+	t.Run("surfaces pagination errors", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			Results:          []*redshiftdataapiservice.GetStatementResultOutput{page("a"), page("b")},
+			ResultPageErrors: map[int]error{1: errors.New("boom")},
+		}
+		it := (&API{DataClient: client}).Results("foo")
+		assert.Equal(t, []string{"a"}, read(it, -1))
+		assert.ErrorIs(t, it.Err(), ResultError)
+	})
+
+	t.Run("stops when closed", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Results: []*redshiftdataapiservice.GetStatementResultOutput{page("a", "b"), page("c")}}
+		it := (&API{DataClient: client}).Results("foo")
+		assert.True(t, it.Next(context.TODO()))
+		assert.NoError(t, it.Close())
+		assert.False(t, it.Next(context.TODO()))
+		assert.Equal(t, 1, client.ResultCalls)
+	})
+
+	t.Run("returns NoResultSetError for statements without result", func(t *testing.T) {
+		it := (&API{DataClient: &redshiftclientmock.MockRedshiftClient{NoResult: true}}).Results("foo")
+		assert.False(t, it.Next(context.TODO()))
+		assert.ErrorIs(t, it.Err(), NoResultSetError)
+	})
+}
+
+func Test_DecodeSuper(t *testing.T) {
+	res, err := DecodeSuper(&redshiftdataapiservice.Field{StringValue: aws.String(`{"foo":[1,"bar"]}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": []interface{}{float64(1), "bar"}}, res)
+
+	res, err = DecodeSuper(&redshiftdataapiservice.Field{IsNull: aws.Bool(true)})
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+
+	_, err = DecodeSuper(&redshiftdataapiservice.Field{StringValue: aws.String(`{"foo"`)})
+	assert.Error(t, err)
+}
+
+func Test_Regions(t *testing.T) {
+	t.Run("returns sorted unique regions", func(t *testing.T) {
+		c := &API{EC2Client: &redshiftclientmock.MockEC2Client{Regions: []string{"us-west-2", "eu-west-1", "us-west-2"}}}
+		res, err := c.Regions(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"eu-west-1", "us-west-2"}, res)
+	})
+
+	t.Run("falls back to the static list without permissions", func(t *testing.T) {
+		c := &API{EC2Client: &redshiftclientmock.MockEC2Client{Unauthorized: true}}
+		res, err := c.Regions(context.TODO())
+		assert.NoError(t, err)
+		assert.Contains(t, res, "us-east-1")
+		assert.NotContains(t, res, "fips-us-east-1")
+		assert.True(t, sort.StringsAreSorted(res))
+	})
+}
+
+func Test_ListStatements(t *testing.T) {
+	created := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	client := &redshiftclientmock.MockRedshiftClient{
+		Statements: []*redshiftdataapiservice.StatementData{
+			{Id: aws.String("foo"), StatementName: aws.String("grafana-dash-1"), QueryString: aws.String("select 1"), Status: aws.String("STARTED"), CreatedAt: &created, UpdatedAt: &created},
+			{Id: aws.String("bar"), QueryString: aws.String("select 2"), Status: aws.String("STARTED")},
+		},
+	}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+
+	res, err := c.ListStatements(context.TODO(), ListStatementsFilter{Status: "STARTED"})
+	assert.NoError(t, err)
+	assert.Equal(t, []StatementSummary{
+		{ID: "foo", Name: "grafana-dash-1", Query: "select 1", Status: "STARTED", CreatedAt: created, UpdatedAt: created},
+		{ID: "bar", Query: "select 2", Status: "STARTED"},
+	}, res)
+	assert.Equal(t, aws.String("STARTED"), client.ListStatementsInput.Status)
+	// Every statement of the IAM role is listed by default
+	assert.Equal(t, aws.Bool(true), client.ListStatementsInput.RoleLevel)
+
+	_, err = c.ListStatements(context.TODO(), ListStatementsFilter{CurrentSessionOnly: true})
+	assert.NoError(t, err)
+	assert.Nil(t, client.ListStatementsInput.Status)
+	assert.Nil(t, client.ListStatementsInput.StatementName)
+	// Only the statements of the current IAM session
+	assert.Equal(t, aws.Bool(false), client.ListStatementsInput.RoleLevel)
+
+	_, err = c.ListStatements(context.TODO(), ListStatementsFilter{StatementNamePrefix: "grafana-"})
+	assert.NoError(t, err)
+	assert.Equal(t, aws.String("grafana-"), client.ListStatementsInput.StatementName)
+	assert.Equal(t, aws.Bool(true), client.ListStatementsInput.RoleLevel)
+}
+
+func Test_PageLimit(t *testing.T) {
+	t.Run("statements", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			Statements: []*redshiftdataapiservice.StatementData{{Id: aws.String("foo")}, {Id: aws.String("bar")}, {Id: aws.String("baz")}},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{MaxPages: 2}, DataClient: client}
+		res, err := c.ListStatements(context.TODO(), ListStatementsFilter{})
+		assert.ErrorIs(t, err, PageLimitError)
+		assert.Equal(t, []StatementSummary{{ID: "foo"}, {ID: "bar"}}, res)
+	})
+
+	t.Run("secrets", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{SecretPages: [][]string{{"foo"}, {"bar"}, {"baz"}}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{MaxPages: 1}, SecretsClient: client}
+		res, err := c.Secrets(context.TODO())
+		assert.ErrorIs(t, err, PageLimitError)
+		assert.Equal(t, []models.ManagedSecret{{ARN: "arn:foo", Name: "foo"}}, res)
+	})
+
+	t.Run("default limit", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{SecretPages: [][]string{{"foo"}, {"bar"}, {"baz"}}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, SecretsClient: client}
+		res, err := c.Secrets(context.TODO())
+		assert.NoError(t, err)
+		assert.Len(t, res, 3)
+	})
+}
+
+func Test_SkippedEntries(t *testing.T) {
+	columns := []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("foo")}, {}, nil}
+
+	t.Run("lenient by default", func(t *testing.T) {
+		logger := &redshiftclientmock.MockLogger{}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{ColumnMetadata: columns}, Logger: logger}
+		res, err := c.Columns(context.TODO(), sqlds.Options{"schema": "public", "table": "bar"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo"}, res)
+		assert.Contains(t, logger.Messages, "list entries skipped")
+		assert.Contains(t, logger.Args, 2)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		c := &API{settings: &models.RedshiftDataSourceSettings{StrictLists: true}, DataClient: &redshiftclientmock.MockRedshiftClient{ColumnMetadata: columns}, Logger: &redshiftclientmock.MockLogger{}}
+		_, err := c.Columns(context.TODO(), sqlds.Options{"schema": "public", "table": "bar"})
+		assert.ErrorIs(t, err, IncompleteListError)
+	})
+}
+
+func Test_PermissionError(t *testing.T) {
+	tests := []struct {
+		description  string
+		err          error
+		isPermission bool
+		action       string
+	}{
+		{
+			description:  "denied action",
+			err:          awserr.New("AccessDeniedException", "User: arn:aws:sts::123456789012:assumed-role/grafana/session is not authorized to perform: redshift-data:ExecuteStatement on resource: arn:aws:redshift:us-east-1:123456789012:cluster:foo", nil),
+			isPermission: true,
+			action:       "redshift-data:ExecuteStatement",
+		},
+		{
+			description:  "denied secret",
+			err:          awserr.New("AccessDeniedException", "User: arn:aws:iam::123456789012:user/grafana is not authorized to perform: secretsmanager:GetSecretValue on resource: foo because no identity-based policy allows the secretsmanager:GetSecretValue action", nil),
+			isPermission: true,
+			action:       "secretsmanager:GetSecretValue",
+		},
+		{
+			description:  "access denied without an action",
+			err:          awserr.New("AccessDenied", "Access Denied", nil),
+			isPermission: true,
+		},
+		{
+			description:  "message only",
+			err:          errors.New("AccessDeniedException: not allowed"),
+			isPermission: true,
+		},
+		{
+			description: "other error",
+			err:         errors.New("syntax error at or near \"selec\""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			client := &redshiftclientmock.MockRedshiftClient{ExecuteError: tt.err}
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+			_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+			assert.ErrorIs(t, err, api.ExecuteError)
+			var permissionErr *PermissionError
+			assert.Equal(t, tt.isPermission, errors.As(err, &permissionErr))
+			if tt.isPermission {
+				assert.Equal(t, tt.action, permissionErr.Action)
+				assert.Contains(t, err.Error(), "IAM")
+			}
+		})
+	}
+
+	t.Run("secrets", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{SecretsError: awserr.New("AccessDeniedException", "User: foo is not authorized to perform: secretsmanager:ListSecrets", nil)}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, SecretsClient: client}
+		_, err := c.Secrets(context.TODO())
+		var permissionErr *PermissionError
+		assert.True(t, errors.As(err, &permissionErr))
+		assert.Equal(t, "secretsmanager:ListSecrets", permissionErr.Action)
+
+		client.SecretsError = awserr.New("AccessDeniedException", "User: foo is not authorized to perform: secretsmanager:GetSecretValue", nil)
+		_, err = c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+		assert.True(t, errors.As(err, &permissionErr))
+		assert.Equal(t, "secretsmanager:GetSecretValue", permissionErr.Action)
+	})
+}
+
+func Test_Canceled(t *testing.T) {
+	canceledErr := awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled)
+	client := &redshiftclientmock.MockRedshiftClient{ExecuteError: canceledErr}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.Execute(ctx, &api.ExecuteQueryInput{Query: "select 1"})
+	assert.ErrorIs(t, err, CanceledError)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, api.ExecuteError)
+
+	_, err = c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+	assert.ErrorIs(t, err, CanceledError)
+
+	client.ExecuteError = errors.New("syntax error")
+	_, err = c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+	assert.NotErrorIs(t, err, CanceledError)
+
+	assert.ErrorIs(t, listError(ctx, ListTablesError, errors.New("boom")), CanceledError)
+	assert.EqualError(t, listError(context.Background(), ListTablesError, errors.New("boom")), "error listing tables: boom")
+}
+
+func Test_ListDatabases(t *testing.T) {
+	t.Run("returns sorted databases", func(t *testing.T) {
+		c := &API{
+			settings:   &models.RedshiftDataSourceSettings{},
+			DataClient: &redshiftclientmock.MockRedshiftClient{Databases: []string{"foo", "bar"}},
+		}
+		res, err := c.Databases(context.TODO(), sqlds.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"bar", "foo"}, res)
+	})
+
+	t.Run("handles an empty response", func(t *testing.T) {
+		c := &API{
+			settings:   &models.RedshiftDataSourceSettings{},
+			DataClient: &redshiftclientmock.MockRedshiftClient{},
+		}
+		res, err := c.Databases(context.TODO(), sqlds.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{}, res)
+	})
+}
+
+func Test_ListSchemas(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"foo": {},
+		"bar": {},
+	}
+	expectedResult := []string{"bar", "foo"}
+	c := &API{
+		settings:   &models.RedshiftDataSourceSettings{},
+		DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
+	}
+	res, err := c.Schemas(context.TODO(), sqlds.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sort.Strings(res)
+	if !cmp.Equal(expectedResult, res) {
+		t.Errorf("unexpected result: %v", cmp.Diff(expectedResult, res))
+	}
+}
+
+func Test_ResourcesCache(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{Resources: map[string]map[string][]string{"foo": {}}}
+	settings := &models.RedshiftDataSourceSettings{DBUser: "user"}
+	c := &API{settings: settings, DataClient: client, cache: newResourceCache(time.Minute)}
+
+	res, err := c.Schemas(context.TODO(), sqlds.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, res)
+
+	client.Resources = map[string]map[string][]string{"bar": {}}
+	res, err = c.Schemas(context.TODO(), sqlds.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, res, "expecting the cached schemas")
+
+	settings.UseManagedSecret = true
+	settings.ManagedSecret = models.ManagedSecret{ARN: "arn"}
+	res, err = c.Schemas(context.TODO(), sqlds.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, res, "expecting the cache not to be shared between auth modes")
+
+	client.Resources = map[string]map[string][]string{"baz": {}}
+	c.ClearCache()
+	res, err = c.Schemas(context.TODO(), sqlds.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz"}, res)
+}
+
+func Test_ListTables(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"foo": {
+			"foofoo": {},
+		},
+		"bar": {
+			"barbar": {},
+		},
+	}
+	expectedResult := []string{"foofoo"}
+	c := &API{
+		settings:   &models.RedshiftDataSourceSettings{},
+		DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
+	}
+	res, err := c.Tables(context.TODO(), sqlds.Options{"schema": "foo"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !cmp.Equal(expectedResult, res) {
+		t.Errorf("unexpected result: %v", cmp.Diff(expectedResult, res))
+	}
+}
+
+func Test_ListTablesFiltered(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"foo": {
+			"foofoo": {},
+			"foobar": {},
+		},
+	}
+	client := &redshiftclientmock.MockRedshiftClient{Resources: resources}
+	c := &API{
+		settings:   &models.RedshiftDataSourceSettings{Database: "default"},
+		DataClient: client,
+	}
+	res, err := c.Tables(context.TODO(), sqlds.Options{"schema": "foo", "tablePattern": "foob%", "database": "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foobar"}, res)
+	assert.Equal(t, "foob%", *client.ListTablesInput.TablePattern)
+	assert.Equal(t, "other", *client.ListTablesInput.Database)
+	assert.Equal(t, "default", *client.ListTablesInput.ConnectedDatabase)
+}
+
+func Test_ListTablesSpecialNames(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"my_schema": {"my_table": {"my_column"}},
+		"MixedCase": {"MixedTable": {"MixedColumn"}},
+	}
+	tests := []struct {
+		description   string
+		schema        string
+		schemaPattern string
+		tables        []string
+	}{
+		{"underscores are escaped", "my_schema", `my\_schema`, []string{"my_table"}},
+		{"mixed case is kept", "MixedCase", "MixedCase", []string{"MixedTable"}},
+		{"quoted identifiers are unquoted", `"MixedCase"`, "MixedCase", []string{"MixedTable"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			client := &redshiftclientmock.MockRedshiftClient{Resources: resources}
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+			res, err := c.Tables(context.TODO(), sqlds.Options{"schema": tt.schema})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.tables, res)
+			assert.Equal(t, tt.schemaPattern, *client.ListTablesInput.SchemaPattern)
+		})
+	}
+}
+
+func Test_ColumnsSpecialNames(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"my_schema": {"my_table": {"my_column"}},
+		"MixedCase": {"MixedTable": {"MixedColumn"}},
+	}
+	tests := []struct {
+		description string
+		schema      string
+		table       string
+		columns     []string
+	}{
+		{"underscores are not escaped", "my_schema", "my_table", []string{"my_column"}},
+		{"mixed case is kept", "MixedCase", "MixedTable", []string{"MixedColumn"}},
+		{"quoted identifiers are unquoted", `"MixedCase"`, `"MixedTable"`, []string{"MixedColumn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources}}
+			res, err := c.Columns(context.TODO(), sqlds.Options{"schema": tt.schema, "table": tt.table})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.columns, res)
+		})
+	}
+}
+
+func Test_identifierName(t *testing.T) {
+	tests := []struct {
+		identifier string
+		name       string
+	}{
+		{"public", "public"},
+		{"MixedCase", "MixedCase"},
+		{`"MixedCase"`, "MixedCase"},
+		{`"with ""quotes"""`, `with "quotes"`},
+		{`"`, `"`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.name, identifierName(tt.identifier), tt.identifier)
+	}
+}
+
+func Test_ListPages(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"a": {"t1": {"c1", "c2", "c3"}, "t2": {}, "t3": {}},
+		"b": {},
+		"c": {},
+	}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources}}
+	tests := []struct {
+		description string
+		list        func(page PageInput) (*Page, error)
+	}{
+		{"schemas", func(page PageInput) (*Page, error) { return c.SchemasPage(context.TODO(), sqlds.Options{}, page) }},
+		{"tables", func(page PageInput) (*Page, error) {
+			return c.TablesPage(context.TODO(), sqlds.Options{"schema": "a"}, page)
+		}},
+		{"columns", func(page PageInput) (*Page, error) {
+			return c.ColumnsPage(context.TODO(), sqlds.Options{"schema": "a", "table": "t1"}, page)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			first, err := tt.list(PageInput{PageSize: 2})
+			assert.NoError(t, err)
+			assert.Len(t, first.Items, 2)
+			assert.NotEmpty(t, first.NextToken)
+
+			last, err := tt.list(PageInput{PageSize: 2, NextToken: first.NextToken})
+			assert.NoError(t, err)
+			assert.Len(t, last.Items, 1)
+			assert.Empty(t, last.NextToken)
+			assert.NotContains(t, first.Items, last.Items[0])
+		})
+	}
+}
+
+func Test_catalogDatabases(t *testing.T) {
+	tests := []struct {
+		description       string
+		options           sqlds.Options
+		database          *string
+		connectedDatabase *string
+	}{
+		{"no database option", sqlds.Options{}, aws.String("default"), nil},
+		{"default database option", sqlds.Options{"database": "__default"}, aws.String("default"), nil},
+		{"same database", sqlds.Options{"database": "default"}, aws.String("default"), nil},
+		{"other database", sqlds.Options{"database": "other"}, aws.String("other"), aws.String("default")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			database, connectedDatabase := catalogDatabases(tt.options, aws.String("default"))
+			assert.Equal(t, tt.database, database)
+			assert.Equal(t, tt.connectedDatabase, connectedDatabase)
+		})
+	}
+}
+
+func Test_ListColumns(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"public": {
+			"foo": {
+				"col1",
+				"col2",
+			},
+			"bar": {
+				"col3",
+			},
+		},
+	}
+	expectedResult := []string{"col1", "col2"}
+	c := &API{
+		settings:   &models.RedshiftDataSourceSettings{},
+		DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
+	}
+	res, err := c.Columns(context.TODO(), sqlds.Options{"schema": "public", "table": "foo"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !cmp.Equal(expectedResult, res) {
+		t.Errorf("unexpected result: %v", cmp.Diff(expectedResult, res))
+	}
+}
+
+func Test_ColumnsWithTypes(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{ColumnMetadata: []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("id"), TypeName: aws.String("int4"), Nullable: aws.Int64(0), Precision: aws.Int64(10)},
+		{Name: aws.String("payload"), TypeName: aws.String("super"), Nullable: aws.Int64(1), Label: aws.String("Payload")},
+		{Name: aws.String("price"), TypeName: aws.String("numeric"), Nullable: aws.Int64(1), Precision: aws.Int64(38), Scale: aws.Int64(2)},
+	}}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+	res, err := c.ColumnsWithTypes(context.TODO(), sqlds.Options{"schema": "public", "table": "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Column{
+		{Name: "id", Type: "int4", Precision: 10, Ordinal: 1, Label: "id"},
+		{Name: "payload", Type: "super", Nullable: true, Ordinal: 2, Label: "Payload"},
+		{Name: "price", Type: "numeric", Nullable: true, Precision: 38, Scale: 2, Ordinal: 3, Label: "price"},
+	}, res)
+	assert.True(t, res[1].IsSuper())
+	assert.False(t, res[0].IsSuper())
+}
+
+func Test_SchemaColumns(t *testing.T) {
+	t.Run("reads svv_columns", func(t *testing.T) {
+		str := func(v string) *redshiftdataapiservice.Field {
+			return &redshiftdataapiservice.Field{StringValue: aws.String(v)}
+		}
+		long := func(v int64) *redshiftdataapiservice.Field {
+			return &redshiftdataapiservice.Field{LongValue: aws.Int64(v)}
+		}
+		null := &redshiftdataapiservice.Field{IsNull: aws.Bool(true)}
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(true)},
+			Results: []*redshiftdataapiservice.GetStatementResultOutput{{Records: [][]*redshiftdataapiservice.Field{
+				{str("bar"), str("id"), str("integer"), str("NO"), long(32), long(0)},
+				{str("bar"), str("name"), str("character varying"), str("YES"), null, null},
+				{str("baz"), str("price"), str("numeric"), str("YES"), long(38), long(2)},
+			}}},
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		res, err := c.SchemaColumns(context.TODO(), "public")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]Column{
+			"bar": {
+				{Name: "id", Type: "integer", Precision: 32, Ordinal: 1, Label: "id"},
+				{Name: "name", Type: "character varying", Nullable: true, Ordinal: 2, Label: "name"},
+			},
+			"baz": {{Name: "price", Type: "numeric", Nullable: true, Precision: 38, Scale: 2, Ordinal: 1, Label: "price"}},
+		}, res)
+		assert.Equal(t, schemaColumnsQuery, *client.ExecuteStatementInput.Sql)
+		assert.Equal(t, []*redshiftdataapiservice.SqlParameter{{Name: aws.String("schema"), Value: aws.String("public")}}, client.ExecuteStatementInput.Parameters)
+	})
+
+	t.Run("describes each table if svv_columns is not accessible", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecuteError: errors.New("permission denied for relation svv_columns"),
+			Resources:    map[string]map[string][]string{"public": {"bar": {"id"}, "baz": {"price"}}},
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		res, err := c.SchemaColumns(context.TODO(), "public")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]Column{"bar": {{Name: "id", Ordinal: 1, Label: "id"}}, "baz": {{Name: "price", Ordinal: 1, Label: "price"}}}, res)
+	})
+}
+
+func Test_DescribeTableSchema(t *testing.T) {
+	columns := []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("id"), TypeName: aws.String("int4")},
+		{Name: aws.String("name"), TypeName: aws.String("varchar"), Nullable: aws.Int64(1)},
+	}
+	expectedColumns := []Column{{Name: "id", Type: "int4", Ordinal: 1, Label: "id"}, {Name: "name", Type: "varchar", Nullable: true, Ordinal: 2, Label: "name"}}
+
+	t.Run("flags the primary key", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ColumnMetadata:          columns,
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(true)},
+			Results: []*redshiftdataapiservice.GetStatementResultOutput{{Records: [][]*redshiftdataapiservice.Field{
+				{{StringValue: aws.String("id")}},
+			}}},
+		}
+		c := &API{settings: testSettings(), DataClient: client}
+		res, err := c.DescribeTableSchema(context.TODO(), sqlds.Options{"schema": "public", "table": `"Users"`})
+		assert.NoError(t, err)
+		assert.Equal(t, &TableSchema{Schema: "public", Table: "Users", Columns: expectedColumns, PrimaryKey: []string{"id"}}, res)
+		assert.True(t, res.IsPrimaryKey("id"))
+		assert.False(t, res.IsPrimaryKey("name"))
+		assert.Equal(t, primaryKeyQuery, *client.ExecuteStatementInput.Sql)
+	})
+
+	t.Run("returns the columns if the catalog is not accessible", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ColumnMetadata: columns, ExecuteError: errors.New("permission denied")}
+		c := &API{settings: testSettings(), DataClient: client, Logger: &redshiftclientmock.MockLogger{}}
+		res, err := c.DescribeTableSchema(context.TODO(), sqlds.Options{"schema": "public", "table": "users"})
+		assert.NoError(t, err)
+		assert.Equal(t, &TableSchema{Schema: "public", Table: "users", Columns: expectedColumns, PrimaryKey: []string{}}, res)
+	})
+}
+
+func Test_ListSecrets(t *testing.T) {
+	expectedSecrets := []models.ManagedSecret{{Name: "foo", ARN: "arn:foo"}}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, SecretsClient: &redshiftclientmock.MockRedshiftClient{Secrets: []string{"foo"}}}
+	secrets, err := c.Secrets(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !cmp.Equal(expectedSecrets, secrets) {
+		t.Errorf("unexpected result: %v", cmp.Diff(expectedSecrets, secrets))
+	}
+}
+
+func Test_ListSecretsPages(t *testing.T) {
+	expectedSecrets := []models.ManagedSecret{{Name: "foo", ARN: "arn:foo"}, {Name: "bar", ARN: "arn:bar"}, {Name: "baz", ARN: "arn:baz"}}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, SecretsClient: &redshiftclientmock.MockRedshiftClient{SecretPages: [][]string{{"foo", "bar"}, {"baz"}}}}
+	secrets, err := c.Secrets(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, expectedSecrets, secrets)
+}
+
+func Test_ListSecretsFilters(t *testing.T) {
+	tests := []struct {
+		description string
+		settings    *models.RedshiftDataSourceSettings
+		expected    []*secretsmanager.Filter
+	}{
+		{
+			"default tag",
+			&models.RedshiftDataSourceSettings{},
+			[]*secretsmanager.Filter{
+				{Key: aws.String("tag-key"), Values: []*string{aws.String("RedshiftQueryOwner")}},
+			},
+		},
+		{
+			"custom tag and value",
+			&models.RedshiftDataSourceSettings{SecretTagKey: "team", SecretTagValue: "analytics"},
+			[]*secretsmanager.Filter{
+				{Key: aws.String("tag-key"), Values: []*string{aws.String("team")}},
+				{Key: aws.String("tag-value"), Values: []*string{aws.String("analytics")}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			client := &redshiftclientmock.MockRedshiftClient{}
+			c := &API{settings: tt.settings, SecretsClient: client}
+			_, err := c.Secrets(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, client.ListSecretsInput.Filters)
+		})
+	}
+}
+
+func Test_GetSecret(t *testing.T) {
+	secretContent := `{"dbClusterIdentifier":"foo","username":"bar"}`
+	c := &API{SecretsClient: &redshiftclientmock.MockRedshiftClient{Secret: secretContent}}
+	secret, err := c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expectedSecret := &models.RedshiftSecret{ClusterIdentifier: "foo", DBUser: "bar"}
+	if !cmp.Equal(expectedSecret, secret) {
+		t.Errorf("unexpected result: %v", cmp.Diff(expectedSecret, secret))
+	}
+}
+
+func Test_GetSecretInvalid(t *testing.T) {
+	tests := []struct {
+		description string
+		content     string
+		binary      bool
+		err         string
+	}{
+		{"not JSON", "foo", false, `invalid managed secret arn: expected a JSON object with "username" and "dbClusterIdentifier" keys: invalid character 'o' in literal false (expecting 'a')`},
+		{"missing username", `{"dbClusterIdentifier":"foo"}`, false, `invalid managed secret arn: missing "username" key`},
+		{"binary secret", `{"dbClusterIdentifier":"foo","username":"bar"}`, true, ""},
+		{"base64 binary secret", base64.StdEncoding.EncodeToString([]byte(`{"dbClusterIdentifier":"foo","username":"bar"}`)), true, ""},
+		{"empty binary secret", "", true, `invalid managed secret arn: expected a JSON object with "username" and "dbClusterIdentifier" keys: unexpected end of JSON input`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{SecretsClient: &redshiftclientmock.MockRedshiftClient{Secret: tt.content, SecretBinary: tt.binary}}
+			secret, err := c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+			if tt.err == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, &models.RedshiftSecret{ClusterIdentifier: "foo", DBUser: "bar"}, secret)
+				return
+			}
+			assert.ErrorIs(t, err, InvalidSecretError)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func Test_settingsFromSecret(t *testing.T) {
+	secretContent := `{"dbClusterIdentifier":"foo","username":"bar","host":"foo.example.com","port":5439,"dbname":"dev"}`
+	client := &redshiftclientmock.MockRedshiftClient{Secret: secretContent}
+	settings := &models.RedshiftDataSourceSettings{UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}}
+	c := &API{settings: settings, SecretsClient: client}
+	res, err := c.settingsFromSecret(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", res.ClusterIdentifier)
+	assert.Equal(t, "dev", res.Database)
+	// The settings of the API aren't modified
+	assert.Equal(t, "", settings.ClusterIdentifier)
+
+	// The secret is only read once
+	res, err = c.settingsFromSecret(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", res.ClusterIdentifier)
+	assert.Equal(t, 1, client.SecretCalls)
+
+	t.Run("no need to read the secret if everything is configured", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Secret: secretContent}
+		settings := &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}}
+		c := &API{settings: settings, SecretsClient: client}
+		res, err := c.settingsFromSecret(context.TODO())
+		assert.NoError(t, err)
+		assert.Same(t, settings, res)
+		assert.Equal(t, 0, client.SecretCalls)
+	})
+
+	t.Run("the secret is read on first use, not when creating the API", func(t *testing.T) {
+		res, err := New(awsds.NewSessionCache(), &models.RedshiftDataSourceSettings{UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}})
+		assert.NoError(t, err)
+		c := res.(*API)
+		client := &redshiftclientmock.MockRedshiftClient{
+			Secret:          secretContent,
+			ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+		}
+		c.DataClient, c.SecretsClient = client, client
+		assert.Equal(t, 0, client.SecretCalls)
+
+		_, err = c.ExecuteStatement(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, client.SecretCalls)
+		assert.Equal(t, "foo", aws.StringValue(client.ExecuteStatementInput.ClusterIdentifier))
+		assert.Equal(t, "dev", aws.StringValue(client.ExecuteStatementInput.Database))
+	})
+
+	t.Run("a secret that can't be read is reported by the health check and read again", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{SecretsError: awserr.New("AccessDeniedException", "not allowed", nil)}
+		c := &API{settings: &models.RedshiftDataSourceSettings{UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}}, SecretsClient: client, DataClient: client}
+		var healthErr *HealthCheckError
+		assert.ErrorAs(t, c.HealthCheck(context.TODO()), &healthErr)
+		assert.Equal(t, HealthCheckSecretUnreadable, healthErr.Stage)
+		err := c.CheckSettings(context.TODO())
+		var permissionErr *PermissionError
+		assert.ErrorAs(t, err, &permissionErr)
+		assert.Contains(t, err.Error(), "unable to read the cluster and database of the managed secret")
+
+		client.SecretsError = nil
+		client.Secret = secretContent
+		assert.NoError(t, c.CheckSettings(context.TODO()))
+	})
+}
+
+func Test_GetSecretCached(t *testing.T) {
+	secretContent := `{"dbClusterIdentifier":"foo","username":"bar"}`
+	client := &redshiftclientmock.MockRedshiftClient{
+		Secret:       secretContent,
+		ExecuteError: awserr.New("AccessDeniedException", "authentication failed", nil),
+	}
+	c := &API{
+		settings: &models.RedshiftDataSourceSettings{
+			UseManagedSecret: true,
+			ManagedSecret:    models.ManagedSecret{ARN: "arn"},
+		},
+		SecretsClient: client,
+		DataClient:    client,
+		secrets:       newSecretCache(time.Minute),
+	}
+
+	for i := 0; i < 2; i++ {
+		secret, err := c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+		assert.NoError(t, err)
+		assert.Equal(t, &models.RedshiftSecret{ClusterIdentifier: "foo", DBUser: "bar"}, secret)
+	}
+	assert.Equal(t, 1, client.SecretCalls)
+
+	_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+	assert.Error(t, err)
+
+	_, err = c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, client.SecretCalls)
+}
+
+func Test_Close(t *testing.T) {
+	c := &API{cache: newResourceCache(time.Minute), secrets: newSecretCache(time.Minute)}
+	c.cache.set("tables", []string{"foo"})
+	c.secrets.set("arn", "v1", &models.RedshiftSecret{DBUser: "foo"})
+
+	assert.NoError(t, c.Close())
+	_, ok := c.cache.get("tables")
+	assert.False(t, ok)
+	_, ok = c.secrets.get("arn")
+	assert.False(t, ok)
+	assert.NoError(t, c.Close())
+
+	assert.NoError(t, (&API{}).Close())
+}
+
+func Test_secretCacheExpires(t *testing.T) {
+	cache := newSecretCache(-time.Second)
+	assert.False(t, cache.set("arn", "v1", &models.RedshiftSecret{}))
+	_, ok := cache.get("arn")
+	assert.False(t, ok)
+	assert.True(t, cache.set("arn", "v2", &models.RedshiftSecret{}))
+}
+
+func Test_GetClusters(t *testing.T) {
+	c := &API{ManagementClient: &redshiftclientmock.MockRedshiftClient{Clusters: []string{"foo", "bar"}}}
+	errC := &API{ManagementClient: &redshiftclientmock.MockRedshiftClientError{}}
+	nilC := &API{ManagementClient: &redshiftclientmock.MockRedshiftClientNil{}}
+	expectedCluster1 := &models.RedshiftCluster{
+		ClusterIdentifier: "foo",
+		Endpoint: models.RedshiftEndpoint{
+			Address: "foo",
+			Port:    123,
+		},
+		Database: "foo",
+	}
+	expectedCluster2 := &models.RedshiftCluster{
+		ClusterIdentifier: "bar",
+		Endpoint: models.RedshiftEndpoint{
+			Address: "bar",
+			Port:    123,
+		},
+		Database: "bar",
+	}
+	tests := []struct {
+		c                *API
+		desc             string
+		errMsg           string
+		expectedClusters []models.RedshiftCluster
+	}{
+		{
+			c:                c,
+			desc:             "Happy Path",
+			expectedClusters: []models.RedshiftCluster{*expectedCluster1, *expectedCluster2},
+		},
+		{
+			c:      errC,
+			desc:   "Error with DescribeCluster",
+			errMsg: "Boom!",
+		},
+		{
+			c:      nilC,
+			desc:   "DescribeCluster returned nil",
+			errMsg: "missing clusters content",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
 			clusters, err := tt.c.Clusters()
 			if tt.errMsg == "" {
 				assert.NoError(t, err)
-				assert.Equal(t, tt.expectedClusters, clusters)
-			} else {
-				assert.Nil(t, clusters)
-				assert.EqualError(t, err, tt.errMsg)
+				assert.Equal(t, tt.expectedClusters, clusters)
+			} else {
+				assert.Nil(t, clusters)
+				assert.EqualError(t, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func Test_copyQuery(t *testing.T) {
+	role := "arn:aws:iam::123456789012:role/redshift-s3"
+	tests := []struct {
+		description string
+		options     CopyOptions
+		query       string
+		err         string
+	}{
+		{
+			description: "all options",
+			options:     CopyOptions{Schema: "public", Table: "Sales", Columns: []string{"id", "amount"}, S3Path: "s3://my-bucket/sales/", IAMRole: role, Format: "csv", Region: "eu-west-1"},
+			query:       `COPY "public"."Sales" ("id", "amount") FROM 's3://my-bucket/sales/' IAM_ROLE 'arn:aws:iam::123456789012:role/redshift-s3' FORMAT AS CSV REGION 'eu-west-1'`,
+		},
+		{
+			description: "json",
+			options:     CopyOptions{Table: "events", S3Path: "s3://my-bucket/events", IAMRole: role, Format: "JSON"},
+			query:       `COPY "events" FROM 's3://my-bucket/events' IAM_ROLE 'arn:aws:iam::123456789012:role/redshift-s3' FORMAT AS JSON 'auto'`,
+		},
+		{
+			description: "quotes are escaped",
+			options:     CopyOptions{Table: `we"ird`, S3Path: "s3://my-bucket/it's", IAMRole: role},
+			query:       `COPY "we""ird" FROM 's3://my-bucket/it''s' IAM_ROLE 'arn:aws:iam::123456789012:role/redshift-s3'`,
+		},
+		{
+			description: "missing table",
+			options:     CopyOptions{S3Path: "s3://my-bucket/sales/", IAMRole: role},
+			err:         "missing table to copy into",
+		},
+		{
+			description: "invalid S3 path",
+			options:     CopyOptions{Table: "sales", S3Path: "https://my-bucket/sales/", IAMRole: role},
+			err:         `invalid S3 path "https://my-bucket/sales/", expected s3://bucket/prefix`,
+		},
+		{
+			description: "invalid IAM role",
+			options:     CopyOptions{Table: "sales", S3Path: "s3://my-bucket/sales/", IAMRole: "redshift-s3"},
+			err:         `invalid IAM role ARN "redshift-s3", expected arn:aws:iam::<account>:role/<name>`,
+		},
+		{
+			description: "unsupported format",
+			options:     CopyOptions{Table: "sales", S3Path: "s3://my-bucket/sales/", IAMRole: role, Format: "xml"},
+			err:         `unsupported format "xml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			query, err := copyQuery(tt.options)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.query, query)
+		})
+	}
+}
+
+func Test_unloadQuery(t *testing.T) {
+	role := "arn:aws:iam::123456789012:role/redshift-s3"
+	query, err := unloadQuery(UnloadOptions{Query: `select * from venue where venuestate = 'NV' and name like '%\_%'`, S3Path: "s3://my-bucket/unload/", IAMRole: role, Format: "parquet"})
+	assert.NoError(t, err)
+	assert.Equal(t, `UNLOAD ('select * from venue where venuestate = ''NV'' and name like ''%\\_%''') TO 's3://my-bucket/unload/' IAM_ROLE 'arn:aws:iam::123456789012:role/redshift-s3' FORMAT AS PARQUET`, query)
+
+	_, err = unloadQuery(UnloadOptions{S3Path: "s3://my-bucket/unload/", IAMRole: role})
+	assert.EqualError(t, err, "missing query to unload")
+
+	_, err = unloadQuery(UnloadOptions{Query: "select 1", S3Path: "s3://my-bucket/unload/", IAMRole: role, Format: "avro"})
+	assert.EqualError(t, err, `unsupported format "avro"`)
+}
+
+func Test_Copy(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+	out, err := c.Copy(context.TODO(), CopyOptions{Table: "sales", S3Path: "s3://my-bucket/sales/", IAMRole: "arn:aws:iam::123456789012:role/redshift-s3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", out.ID)
+	assert.Equal(t, `COPY "sales" FROM 's3://my-bucket/sales/' IAM_ROLE 'arn:aws:iam::123456789012:role/redshift-s3'`, *client.ExecuteStatementInput.Sql)
+
+	client.ExecuteStatementInput = nil
+	_, err = c.Unload(context.TODO(), UnloadOptions{Query: "select 1", S3Path: "bucket", IAMRole: "arn:aws:iam::123456789012:role/redshift-s3"})
+	assert.Error(t, err)
+	assert.Nil(t, client.ExecuteStatementInput)
+}
+
+func Test_GetResultPageRetry(t *testing.T) {
+	page := func(value string) *redshiftdataapiservice.GetStatementResultOutput {
+		return &redshiftdataapiservice.GetStatementResultOutput{Records: [][]*redshiftdataapiservice.Field{{{StringValue: aws.String(value)}}}}
+	}
+
+	t.Run("retries a failing middle page with its token", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			Results:            []*redshiftdataapiservice.GetStatementResultOutput{page("a"), page("b"), page("c")},
+			ResultPageFailures: map[int][]error{1: {awserr.New(request.ErrCodeRequestError, "connection reset by peer", errors.New("read: connection reset by peer"))}},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, DataClient: client}
+		res, err := c.GetResult(context.TODO(), "foo", 0)
+		assert.NoError(t, err)
+		assert.Len(t, res.Records, 3)
+		assert.Equal(t, 4, client.ResultCalls)
+	})
+
+	t.Run("reports an expired token", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			Results:            []*redshiftdataapiservice.GetStatementResultOutput{page("a"), page("b")},
+			ResultPageFailures: map[int][]error{1: {awserr.New(redshiftdataapiservice.ErrCodeValidationException, "The next token is invalid or has expired", nil)}},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, DataClient: client}
+		_, err := c.GetResult(context.TODO(), "foo", 0)
+		assert.ErrorIs(t, err, ExpiredResultTokenError)
+		assert.ErrorIs(t, err, ResultError)
+		assert.Equal(t, 2, client.ResultCalls)
+	})
+
+	t.Run("reports a result over the size limit", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			Results:          []*redshiftdataapiservice.GetStatementResultOutput{page("a")},
+			ResultPageErrors: map[int]error{0: awserr.New(redshiftdataapiservice.ErrCodeValidationException, "Query result size exceeds the limit of 100 MB", nil)},
+		}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, DataClient: client}
+		_, err := c.GetResult(context.TODO(), "foo", 0)
+		assert.ErrorIs(t, err, ResultTooLargeError)
+		assert.ErrorIs(t, err, ResultError)
+		assert.NotErrorIs(t, err, ExpiredResultTokenError)
+		assert.Contains(t, err.Error(), "UNLOAD")
+		assert.Equal(t, 1, client.ResultCalls)
+	})
+
+	t.Run("other errors mentioning a size aren't a result over the size limit", func(t *testing.T) {
+		for _, resultErr := range []error{
+			awserr.New(redshiftdataapiservice.ErrCodeValidationException, "The page size exceeds the maximum of 1000", nil),
+			awserr.New(redshiftdataapiservice.ErrCodeDatabaseConnectionException, "Query result size exceeds the limit of 100 MB", nil),
+		} {
+			client := &redshiftclientmock.MockRedshiftClient{
+				Results:          []*redshiftdataapiservice.GetStatementResultOutput{page("a")},
+				ResultPageErrors: map[int]error{0: resultErr},
+			}
+			c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, DataClient: client}
+			_, err := c.GetResult(context.TODO(), "foo", 0)
+			assert.ErrorIs(t, err, ResultError)
+			assert.NotErrorIs(t, err, ResultTooLargeError, resultErr.Error())
+		}
+	})
+}
+
+func Test_StatementError(t *testing.T) {
+	tests := []struct {
+		description string
+		status      *redshiftdataapiservice.DescribeStatementOutput
+		expected    *StatementError
+	}{
+		{
+			description: "failed with an error",
+			status:      &redshiftdataapiservice.DescribeStatementOutput{Status: aws.String("FAILED"), Error: aws.String(`ERROR: relation "foo" does not exist [ErrorId: 1-6411d11f-0a2b3c4d5e6f]`)},
+			expected:    &StatementError{State: "FAILED", Message: `ERROR: relation "foo" does not exist [ErrorId: 1-6411d11f-0a2b3c4d5e6f]`, ErrorID: "1-6411d11f-0a2b3c4d5e6f"},
+		},
+		{
+			description: "failed without an error",
+			status:      &redshiftdataapiservice.DescribeStatementOutput{Status: aws.String("FAILED")},
+			expected:    &StatementError{State: "FAILED", Message: "statement failed without an error message"},
+		},
+		{
+			description: "aborted without an error",
+			status:      &redshiftdataapiservice.DescribeStatementOutput{Status: aws.String("ABORTED")},
+			expected:    &StatementError{State: "ABORTED", Message: "statement aborted without an error message"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{DescribeStatementOutput: tt.status}}
+			status, err := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+			assert.True(t, status.Finished)
+			var statementErr *StatementError
+			assert.True(t, errors.As(err, &statementErr))
+			assert.Equal(t, tt.expected, statementErr)
+		})
+	}
+}
+
+func Test_IncompleteResponses(t *testing.T) {
+	t.Run("execute without output", func(t *testing.T) {
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{}}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.ErrorIs(t, err, api.ExecuteError)
+		assert.EqualError(t, err, "error executing query: the response has no statement ID")
+	})
+
+	t.Run("execute without ID", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.ErrorIs(t, err, api.ExecuteError)
+		_, err = c.BatchExecute(context.TODO(), []string{"select 1"})
+		assert.ErrorIs(t, err, api.ExecuteError)
+	})
+
+	t.Run("status without output", func(t *testing.T) {
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{}}
+		_, err := c.Status(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.ErrorIs(t, err, api.StatusError)
+	})
+
+	t.Run("status without state", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo")}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		_, err := c.Status(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.ErrorIs(t, err, api.StatusError)
+		assert.EqualError(t, err, "error getting query query status: the response of statement foo has no status")
+	})
+}
+
+func Test_ListTablesDefaultSchema(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"public":    {"pub": {}},
+		"analytics": {"events": {}},
+	}
+	tests := []struct {
+		description   string
+		defaultSchema string
+		options       sqlds.Options
+		tables        []string
+	}{
+		{"public without a default schema", "", sqlds.Options{}, []string{"pub"}},
+		{"the default schema if configured", "analytics", sqlds.Options{}, []string{"events"}},
+		{"the selected schema over the default one", "analytics", sqlds.Options{"schema": "public"}, []string{"pub"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{
+				settings:   &models.RedshiftDataSourceSettings{DefaultSchema: tt.defaultSchema},
+				DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
+			}
+			res, err := c.Tables(context.TODO(), tt.options)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.tables, res)
+		})
+	}
+}
+
+func Test_ListColumnsPrefix(t *testing.T) {
+	resources := map[string]map[string][]string{
+		"public": {"foo": {"user_id", "UserName", "created_at"}},
+	}
+	c := &API{
+		settings:   &models.RedshiftDataSourceSettings{},
+		DataClient: &redshiftclientmock.MockRedshiftClient{Resources: resources},
+		cache:      newResourceCache(time.Minute),
+	}
+	tests := []struct {
+		prefix  string
+		columns []string
+	}{
+		{"", []string{"user_id", "UserName", "created_at"}},
+		{"user", []string{"user_id", "UserName"}},
+		{"CREATED", []string{"created_at"}},
+		{"missing", []string{}},
+	}
+	for _, tt := range tests {
+		res, err := c.Columns(context.TODO(), sqlds.Options{"schema": "public", "table": "foo", "columnPrefix": tt.prefix})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, tt.columns, res, tt.prefix)
+	}
+}
+
+func Test_TablesWithType(t *testing.T) {
+	c := &API{
+		settings: &models.RedshiftDataSourceSettings{},
+		DataClient: &redshiftclientmock.MockRedshiftClient{
+			Resources:  map[string]map[string][]string{"public": {"sales": {}, "sales_view": {}, "sales_mv": {}}},
+			TableTypes: map[string]string{"sales_view": "VIEW", "sales_mv": "MATERIALIZED VIEW"},
+		},
+	}
+	res, err := c.TablesWithType(context.TODO(), sqlds.Options{"schema": "public"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []TableInfo{
+		{Name: "sales", Type: "TABLE"},
+		{Name: "sales_view", Type: "VIEW"},
+		{Name: "sales_mv", Type: "MATERIALIZED VIEW"},
+	}, res)
+}
+
+func Test_RetryBudget(t *testing.T) {
+	throttled := awserr.New("ThrottlingException", "Rate exceeded", nil)
+	newClient := func() *redshiftclientmock.MockRedshiftClient {
+		return &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			ExecuteErrors:           []error{throttled, throttled},
+			DescribeStatementErrors: []error{throttled, throttled, throttled},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished)},
+		}
+	}
+
+	t.Run("the retries of a query share the budget", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user", RetryDelay: 1, RetryBudget: 3}, DataClient: client}
+		_, err := c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}})
+		assert.Error(t, err)
+		// 2 retries to submit the statement and 1 to get its status
+		assert.Equal(t, 5, client.Calls)
+	})
+
+	t.Run("a negative budget only limits the retries of each call", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user", RetryDelay: 1, RetryBudget: -1}, DataClient: client}
+		_, err := c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 7, client.Calls)
+	})
+
+	t.Run("the budget of the context is used", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user", RetryDelay: 1}, DataClient: client}
+		_, err := c.Execute(WithRetryBudget(context.TODO(), 1), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.ErrorIs(t, err, api.ExecuteError)
+		assert.Equal(t, 2, client.Calls)
+	})
+}
+
+func Test_QuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		quoted string
+	}{
+		{"sales", `"sales"`},
+		{"MixedCase", `"MixedCase"`},
+		{"with space", `"with space"`},
+		{"a.b", `"a.b"`},
+		{`"quoted"`, `"""quoted"""`},
+		{`foo"; DROP TABLE users; --`, `"foo""; DROP TABLE users; --"`},
+		{`'`, `"'"`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.quoted, QuoteIdentifier(tt.name), tt.name)
+	}
+}
+
+func Test_QuoteSchemaTable(t *testing.T) {
+	assert.Equal(t, `"sales"`, QuoteSchemaTable("", "sales"))
+	assert.Equal(t, `"public"."sales"`, QuoteSchemaTable("public", "sales"))
+	assert.Equal(t, `"x"".""y"."t"`, QuoteSchemaTable(`x"."y`, "t"))
+}
+
+// cancelRecorder sends the ID of the canceled statements to canceled
+type cancelRecorder struct {
+	*redshiftclientmock.MockRedshiftClient
+	canceled chan string
+}
+
+func (r *cancelRecorder) CancelStatementWithContext(_ aws.Context, input *redshiftdataapiservice.CancelStatementInput, _ ...request.Option) (*redshiftdataapiservice.CancelStatementOutput, error) {
+	r.canceled <- *input.Id
+	return &redshiftdataapiservice.CancelStatementOutput{Status: aws.Bool(true)}, nil
+}
+
+func Test_CancelInflightStatements(t *testing.T) {
+	newAPI := func() (*API, *cancelRecorder) {
+		client := &cancelRecorder{
+			MockRedshiftClient: &redshiftclientmock.MockRedshiftClient{
+				ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+				DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished)},
+			},
+			canceled: make(chan string, 1),
+		}
+		return &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}, client
+	}
+
+	t.Run("cancels the statements when the context is canceled", func(t *testing.T) {
+		c, client := newAPI()
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := c.Execute(WithStatementCancellation(ctx), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, c.inflight.len())
+
+		cancel()
+		select {
+		case id := <-client.canceled:
+			assert.Equal(t, "foo", id)
+		case <-time.After(time.Second):
+			t.Fatal("the statement was not canceled")
+		}
+		assert.Equal(t, 0, c.inflight.len())
+	})
+
+	t.Run("doesn't cancel the finished statements", func(t *testing.T) {
+		c, client := newAPI()
+		ctx, cancel := context.WithCancel(context.Background())
+		output, err := c.Execute(WithStatementCancellation(ctx), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		_, err = c.StatementStatus(ctx, output)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, c.inflight.len())
+
+		cancel()
+		select {
+		case <-client.canceled:
+			t.Fatal("the finished statement was canceled")
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+
+	t.Run("doesn't track the statements of contexts that can't be canceled", func(t *testing.T) {
+		c, _ := newAPI()
+		_, err := c.Execute(WithStatementCancellation(context.Background()), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, c.inflight.len())
+	})
+
+	t.Run("a bare Execute survives the cancellation of its context", func(t *testing.T) {
+		c, client := newAPI()
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := c.Execute(ctx, &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, c.inflight.len())
+
+		cancel()
+		select {
+		case <-client.canceled:
+			t.Fatal("the statement was canceled")
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+}
+
+func Test_ColumnsWithTypesOrder(t *testing.T) {
+	// The columns are returned in the order of the table definition, skipped entries aside
+	client := &redshiftclientmock.MockRedshiftClient{ColumnMetadata: []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("z")}, {Name: aws.String("a")}, nil, {Name: aws.String("m")},
+	}}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client, Logger: &redshiftclientmock.MockLogger{}}
+	res, err := c.ColumnsWithTypes(context.TODO(), sqlds.Options{"schema": "public", "table": "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Column{{Name: "z", Ordinal: 1, Label: "z"}, {Name: "a", Ordinal: 2, Label: "a"}, {Name: "m", Ordinal: 3, Label: "m"}}, res)
+}
+
+func Test_DisableManagedSecrets(t *testing.T) {
+	settings := &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user", DisableManagedSecrets: true}
+	res, err := New(awsds.NewSessionCache(), settings)
+	assert.NoError(t, err)
+	c := res.(*API)
+	assert.Nil(t, c.SecretsClient)
+
+	_, err = c.Secrets(context.TODO())
+	assert.ErrorIs(t, err, ManagedSecretsDisabledError)
+	_, err = c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+	assert.ErrorIs(t, err, ManagedSecretsDisabledError)
+}
+
+func Test_LimitQuery(t *testing.T) {
+	tests := []struct {
+		description string
+		query       string
+		maxRows     int
+		expected    string
+	}{
+		{"limits a select", "SELECT * FROM sales", 100, "SELECT * FROM sales\nLIMIT 101"},
+		{"removes the trailing semicolon", " select * from sales; ", 100, "select * from sales\nLIMIT 101"},
+		{"keeps a comment at the end", "SELECT * FROM sales -- all of them", 10, "SELECT * FROM sales -- all of them\nLIMIT 11"},
+		{"keeps an existing limit", "SELECT * FROM sales ORDER BY id LIMIT 5", 100, "SELECT * FROM sales ORDER BY id LIMIT 5"},
+		{"keeps other statements", "INSERT INTO sales VALUES (1)", 100, "INSERT INTO sales VALUES (1)"},
+		{"keeps several statements", "SELECT 1; SELECT 2", 100, "SELECT 1; SELECT 2"},
+		{"keeps the query without a maximum", "SELECT * FROM sales", 0, "SELECT * FROM sales"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			assert.Equal(t, tt.expected, LimitQuery(tt.query, tt.maxRows))
+		})
+	}
+}
+
+func Test_ExecuteAndWaitMaxRows(t *testing.T) {
+	field := func(v string) []*redshiftdataapiservice.Field {
+		return []*redshiftdataapiservice.Field{{StringValue: aws.String(v)}}
+	}
+	newClient := func() *redshiftclientmock.MockRedshiftClient {
+		return &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(true)},
+			Results: []*redshiftdataapiservice.GetStatementResultOutput{
+				{Records: [][]*redshiftdataapiservice.Field{field("a"), field("b")}, NextToken: aws.String("1")},
+				{Records: [][]*redshiftdataapiservice.Field{field("c")}},
+			},
+		}
+	}
+	tests := []struct {
+		maxRows   int
+		records   int
+		truncated bool
+	}{
+		{0, 3, false},
+		{2, 2, true},
+		{3, 3, false},
+	}
+	for _, tt := range tests {
+		c := &API{settings: testSettings(), DataClient: newClient()}
+		res, err := c.ExecuteAndWait(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}, MaxRows: tt.maxRows})
+		assert.NoError(t, err)
+		assert.Len(t, res.Records, tt.records, tt.maxRows)
+		assert.Equal(t, tt.truncated, res.Truncated, tt.maxRows)
+	}
+}
+
+func Test_StatementStatusRedshiftIDs(t *testing.T) {
+	tests := []struct {
+		description string
+		output      *redshiftdataapiservice.DescribeStatementOutput
+		pid         int64
+		queryID     int64
+	}{
+		{
+			"started statement",
+			&redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringStarted), RedshiftPid: aws.Int64(1073815778), RedshiftQueryId: aws.Int64(4242)},
+			1073815778,
+			4242,
+		},
+		{
+			"submitted statement",
+			&redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringSubmitted)},
+			0,
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{DescribeStatementOutput: tt.output}}
+			status, err := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pid, status.RedshiftPid)
+			assert.Equal(t, tt.queryID, status.RedshiftQueryID)
+		})
+	}
+}
+
+func Test_AutoResume(t *testing.T) {
+	resumePollInterval = time.Millisecond
+	defer func() { resumePollInterval = 15 * time.Second }()
+	paused := awserr.New("ValidationException", "Cluster foo is paused", nil)
+	newAPI := func(client *redshiftclientmock.MockRedshiftClient, settings *models.RedshiftDataSourceSettings) *API {
+		settings.ClusterIdentifier = "foo"
+		return &API{settings: settings, DataClient: client, ManagementClient: client}
+	}
+
+	t.Run("resumes the cluster and runs the statement", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecuteErrors:   []error{paused},
+			ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			ClusterStatuses: []string{"resuming", "resuming", "available"},
+		}
+		c := newAPI(client, &models.RedshiftDataSourceSettings{AutoResume: true})
+		res, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", res.ID)
+		assert.True(t, client.Resumed)
+	})
+
+	t.Run("doesn't resume the cluster by default", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecuteErrors: []error{paused}}
+		c := newAPI(client, &models.RedshiftDataSourceSettings{})
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.ErrorIs(t, err, ClusterPausedError)
+		assert.False(t, client.Resumed)
+	})
+
+	t.Run("names the permissions to resume the cluster", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecuteErrors: []error{paused},
+			ResumeError:   awserr.New("AccessDenied", "not allowed", nil),
+		}
+		c := newAPI(client, &models.RedshiftDataSourceSettings{AutoResume: true})
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.ErrorIs(t, err, ClusterResumeError)
+		assert.ErrorIs(t, err, ClusterPausedError)
+		var permissionErr *PermissionError
+		assert.ErrorAs(t, err, &permissionErr)
+		assert.Equal(t, "redshift:ResumeCluster and redshift:DescribeClusters", permissionErr.Action)
+	})
+
+	t.Run("gives up after the timeout", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{
+			ExecuteErrors:   []error{paused},
+			ClusterStatuses: []string{"resuming"},
+		}
+		c := newAPI(client, &models.RedshiftDataSourceSettings{AutoResume: true, AutoResumeTimeout: 1})
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.ErrorIs(t, err, ClusterResumeError)
+		assert.EqualError(t, err, "error executing query: the cluster is paused, resume it to run queries: resuming the cluster failed: the cluster is still not available after 1s")
+	})
+}
+
+func Test_ListErrors(t *testing.T) {
+	boom := errors.New("boom")
+	client := &redshiftclientmock.MockRedshiftClient{ListError: boom, SecretsError: boom}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client, SecretsClient: client}
+	options := sqlds.Options{"schema": "public", "table": "foo"}
+	tests := []struct {
+		operation string
+		call      func() error
+		sentinel  error
+	}{
+		{"databases", func() error { _, err := c.Databases(context.TODO(), options); return err }, ListDatabasesError},
+		{"schemas", func() error { _, err := c.Schemas(context.TODO(), options); return err }, ListSchemasError},
+		{"tables", func() error { _, err := c.Tables(context.TODO(), options); return err }, ListTablesError},
+		{"columns", func() error { _, err := c.Columns(context.TODO(), options); return err }, DescribeTableError},
+		{"secrets", func() error { _, err := c.Secrets(context.TODO()); return err }, ListSecretsError},
+		{"statements", func() error { _, err := c.ListStatements(context.TODO(), ListStatementsFilter{}); return err }, ListStatementsError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			err := tt.call()
+			assert.ErrorIs(t, err, tt.sentinel)
+			assert.Contains(t, err.Error(), "boom")
+		})
+	}
+
+	t.Run("missing permissions wrap the sentinel", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ListError: awserr.New("AccessDeniedException", "not authorized to perform: redshift-data:ListTables", nil)}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		_, err := c.Tables(context.TODO(), options)
+		var permissionErr *PermissionError
+		assert.ErrorAs(t, err, &permissionErr)
+		assert.ErrorIs(t, err, ListTablesError)
+	})
+}
+
+func Test_ManagedSecretAndDBUser(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+	c := &API{
+		settings: &models.RedshiftDataSourceSettings{
+			ClusterIdentifier: "cluster",
+			Database:          "db",
+			UseManagedSecret:  true,
+			ManagedSecret:     models.ManagedSecret{ARN: "arn:secret"},
+			DBUser:            "user",
+		},
+		DataClient:       client,
+		ManagementClient: client,
+	}
+	assert.NoError(t, validateSettings(c.settings))
+
+	// The statements only use the secret
+	_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:secret", aws.StringValue(client.ExecuteStatementInput.SecretArn))
+	assert.Nil(t, client.ExecuteStatementInput.DbUser)
+
+	// The temporary credentials are of the database user
+	res, err := c.ClusterCredentials(context.TODO(), 900)
+	assert.NoError(t, err)
+	assert.Equal(t, "IAM:user", aws.StringValue(res.DbUser))
+	assert.Equal(t, &redshift.GetClusterCredentialsInput{
+		ClusterIdentifier: aws.String("cluster"),
+		DbUser:            aws.String("user"),
+		DbName:            aws.String("db"),
+		DurationSeconds:   aws.Int64(900),
+	}, client.ClusterCredentialsInput)
+
+	// The temporary credentials are of the cluster and the database of the overrides
+	ctx, err := c.WithOverrides(context.TODO(), Overrides{ClusterIdentifier: "other", Database: "otherdb"})
+	assert.NoError(t, err)
+	_, err = c.ClusterCredentials(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &redshift.GetClusterCredentialsInput{
+		ClusterIdentifier: aws.String("other"),
+		DbUser:            aws.String("user"),
+		DbName:            aws.String("otherdb"),
+	}, client.ClusterCredentialsInput)
+
+	ctx, err = c.WithOverrides(context.TODO(), Overrides{WorkgroupName: "workgroup"})
+	assert.NoError(t, err)
+	_, err = c.ClusterCredentials(ctx, 0)
+	assert.ErrorIs(t, err, UnsupportedAuthError)
+
+	c.settings.DBUser = ""
+	_, err = c.ClusterCredentials(context.TODO(), 0)
+	assert.EqualError(t, err, "missing database user, it's required to get temporary credentials")
+}
+
+func Test_DescribeCluster(t *testing.T) {
+	t.Run("returns the provisioned cluster", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ClusterDetails: &redshift.Cluster{
+			ClusterIdentifier: aws.String("cluster"),
+			ClusterStatus:     aws.String("available"),
+			NodeType:          aws.String("ra3.xlplus"),
+			NumberOfNodes:     aws.Int64(2),
+			Endpoint:          &redshift.Endpoint{Address: aws.String("cluster.example.com"), Port: aws.Int64(5439)},
+		}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster"}, ManagementClient: client}
+		res, err := c.DescribeCluster(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &ClusterInfo{
+			Identifier:    "cluster",
+			Status:        "available",
+			Endpoint:      models.RedshiftEndpoint{Address: "cluster.example.com", Port: 5439},
+			NodeType:      "ra3.xlplus",
+			NumberOfNodes: 2,
+		}, res)
+	})
+
+	t.Run("returns the Serverless workgroup", func(t *testing.T) {
+		serverless := &redshiftclientmock.MockRedshiftServerlessClient{Workgroup: &redshiftserverless.Workgroup{
+			WorkgroupName: aws.String("workgroup"),
+			Status:        aws.String("AVAILABLE"),
+			BaseCapacity:  aws.Int64(32),
+			Endpoint:      &redshiftserverless.Endpoint{Address: aws.String("workgroup.example.com"), Port: aws.Int64(5439)},
+		}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup"}, ServerlessClient: serverless}
+		res, err := c.DescribeCluster(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &ClusterInfo{
+			Identifier:   "workgroup",
+			Status:       "AVAILABLE",
+			Endpoint:     models.RedshiftEndpoint{Address: "workgroup.example.com", Port: 5439},
+			Serverless:   true,
+			BaseCapacity: 32,
+		}, res)
+	})
+
+	t.Run("errors if the cluster isn't found", func(t *testing.T) {
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster"}, ManagementClient: &redshiftclientmock.MockRedshiftClient{}}
+		_, err := c.DescribeCluster(context.Background())
+		assert.EqualError(t, err, "cluster cluster not found")
+	})
+
+	t.Run("names the missing permission", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{DescribeClustersError: awserr.New("AccessDeniedException", "not authorized to perform: redshift:DescribeClusters", nil)}
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster"}, ManagementClient: client}
+		_, err := c.DescribeCluster(context.Background())
+		var permErr *PermissionError
+		assert.True(t, errors.As(err, &permErr))
+		assert.Equal(t, "redshift:DescribeClusters", permErr.Action)
+	})
+}
+
+// slowLister blocks ListTables until its context is done
+type slowLister struct {
+	*redshiftclientmock.MockRedshiftClient
+}
+
+func (s *slowLister) ListTablesWithContext(ctx aws.Context, _ *redshiftdataapiservice.ListTablesInput, _ ...request.Option) (*redshiftdataapiservice.ListTablesOutput, error) {
+	<-ctx.Done()
+	return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+}
+
+func Test_CallTimeout(t *testing.T) {
+	settings := &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", CallTimeout: 1}
+
+	t.Run("stops a slow call", func(t *testing.T) {
+		c := &API{settings: settings, DataClient: &slowLister{&redshiftclientmock.MockRedshiftClient{}}}
+		_, err := c.Tables(context.Background(), sqlds.Options{"schema": "public"})
+		assert.ErrorIs(t, err, CallTimeoutError)
+		assert.Contains(t, err.Error(), "ListTables did not complete within 1s")
+	})
+
+	t.Run("keeps the cancelation of the request", func(t *testing.T) {
+		c := &API{settings: settings, DataClient: &slowLister{&redshiftclientmock.MockRedshiftClient{}}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := c.Tables(ctx, sqlds.Options{"schema": "public"})
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, CallTimeoutError))
+	})
+
+	t.Run("doesn't limit the calls by default", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Resources: map[string]map[string][]string{"public": {"foo": {}}}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"}, DataClient: client}
+		res, err := c.Tables(context.Background(), sqlds.Options{"schema": "public"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo"}, res)
+	})
+}
+
+func Test_NewRegion(t *testing.T) {
+	newSettings := func() *models.RedshiftDataSourceSettings {
+		return &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"}
+	}
+
+	t.Run("normalizes the region", func(t *testing.T) {
+		settings := newSettings()
+		settings.Region = " US-East-1 "
+		_, err := New(awsds.NewSessionCache(), settings)
+		assert.NoError(t, err)
+		assert.Equal(t, "us-east-1", settings.Region)
+	})
+
+	t.Run("rejects an unknown region", func(t *testing.T) {
+		settings := newSettings()
+		settings.Region = "us-east-9"
+		_, err := New(awsds.NewSessionCache(), settings)
+		assert.ErrorIs(t, err, UnsupportedRegionError)
+		assert.Contains(t, err.Error(), `unsupported region "us-east-9" for redshift, valid regions are: `)
+		assert.Contains(t, err.Error(), "eu-west-1")
+	})
+
+	t.Run("checks the default region", func(t *testing.T) {
+		settings := newSettings()
+		settings.Region = "default"
+		settings.DefaultRegion = "mars-1"
+		_, err := New(awsds.NewSessionCache(), settings)
+		assert.ErrorIs(t, err, UnsupportedRegionError)
+	})
+
+	t.Run("checks the secrets region", func(t *testing.T) {
+		settings := newSettings()
+		settings.Region = "us-east-1"
+		settings.SecretsRegion = "mars-1"
+		_, err := New(awsds.NewSessionCache(), settings)
+		assert.ErrorIs(t, err, UnsupportedRegionError)
+	})
+
+	t.Run("allows an unknown region if enabled", func(t *testing.T) {
+		settings := newSettings()
+		settings.Region = "us-east-9"
+		settings.AllowUnknownRegion = true
+		_, err := New(awsds.NewSessionCache(), settings)
+		assert.NoError(t, err)
+	})
+}
+
+func Test_ListSchemasPattern(t *testing.T) {
+	resources := map[string]map[string][]string{"sales_eu": {}, "salesxeu": {}, "public": {}}
+	newAPI := func() (*API, *redshiftclientmock.MockRedshiftClient) {
+		client := &redshiftclientmock.MockRedshiftClient{Resources: resources}
+		return &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client, cache: newResourceCache(time.Minute)}, client
+	}
+
+	t.Run("lists every schema by default", func(t *testing.T) {
+		c, client := newAPI()
+		res, err := c.Schemas(context.TODO(), sqlds.Options{})
+		assert.NoError(t, err)
+		assert.Len(t, res, 3)
+		assert.Nil(t, client.ListSchemasInput.SchemaPattern)
+	})
+
+	t.Run("passes the schema pattern", func(t *testing.T) {
+		c, client := newAPI()
+		res, err := c.Schemas(context.TODO(), sqlds.Options{"schemaPattern": "pub%"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"public"}, res)
+		assert.Equal(t, "pub%", aws.StringValue(client.ListSchemasInput.SchemaPattern))
+	})
+
+	t.Run("escapes the wildcards of the schema prefix", func(t *testing.T) {
+		c, client := newAPI()
+		res, err := c.Schemas(context.TODO(), sqlds.Options{"schemaPrefix": "sales_"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"sales_eu"}, res)
+		assert.Equal(t, `sales\_%`, aws.StringValue(client.ListSchemasInput.SchemaPattern))
+	})
+
+	t.Run("caches the schemas by pattern", func(t *testing.T) {
+		c, _ := newAPI()
+		_, err := c.Schemas(context.TODO(), sqlds.Options{"schemaPattern": "pub%"})
+		assert.NoError(t, err)
+		res, err := c.Schemas(context.TODO(), sqlds.Options{})
+		assert.NoError(t, err)
+		assert.Len(t, res, 3)
+	})
+}
+
+func Test_NewResultSet(t *testing.T) {
+	columns := []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("name"), TypeName: aws.String("varchar"), Nullable: aws.Int64(1)},
+		{Name: aws.String("count"), TypeName: aws.String("int8"), Nullable: aws.Int64(0)},
+		{Name: aws.String("ratio"), TypeName: aws.String("float8"), Nullable: aws.Int64(1)},
+		{Name: aws.String("enabled"), TypeName: aws.String("bool"), Nullable: aws.Int64(1)},
+		{Name: aws.String("data"), TypeName: aws.String("varbyte"), Nullable: aws.Int64(1)},
+	}
+
+	t.Run("converts every scalar type and nulls", func(t *testing.T) {
+		res, err := NewResultSet(columns, [][]*redshiftdataapiservice.Field{
+			{{StringValue: aws.String("foo")}, {LongValue: aws.Int64(2)}, {DoubleValue: aws.Float64(0.5)}, {BooleanValue: aws.Bool(true)}, {BlobValue: []byte("bar")}},
+			{{IsNull: aws.Bool(true)}, {LongValue: aws.Int64(0)}, {IsNull: aws.Bool(true)}, {IsNull: aws.Bool(true)}, {IsNull: aws.Bool(true)}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []ResultColumn{
+			{Name: "name", Type: "varchar", Nullable: true},
+			{Name: "count", Type: "int8"},
+			{Name: "ratio", Type: "float8", Nullable: true},
+			{Name: "enabled", Type: "bool", Nullable: true},
+			{Name: "data", Type: "varbyte", Nullable: true},
+		}, res.Columns)
+		assert.Equal(t, [][]interface{}{
+			{"foo", int64(2), 0.5, true, []byte("bar")},
+			{nil, int64(0), nil, nil, nil},
+		}, res.Rows)
+	})
+
+	t.Run("errors on a field without a value", func(t *testing.T) {
+		_, err := NewResultSet(columns[:1], [][]*redshiftdataapiservice.Field{{{}}})
+		assert.ErrorIs(t, err, ResultError)
+		assert.EqualError(t, err, "error getting query result: column name of record 0: field without a value")
+	})
+
+	t.Run("errors on a record with missing fields", func(t *testing.T) {
+		_, err := NewResultSet(columns, [][]*redshiftdataapiservice.Field{{{StringValue: aws.String("foo")}}})
+		assert.EqualError(t, err, "error getting query result: record 0 has 1 fields, expected 5")
+	})
+}
+
+func Test_GetResultSet(t *testing.T) {
+	client := &redshiftclientmock.MockRedshiftClient{Results: []*redshiftdataapiservice.GetStatementResultOutput{
+		{
+			ColumnMetadata: []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("id"), TypeName: aws.String("int4")}},
+			Records:        [][]*redshiftdataapiservice.Field{{{LongValue: aws.Int64(1)}}, {{LongValue: aws.Int64(2)}}},
+		},
+	}}
+	c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+	res, err := c.GetResultSet(context.Background(), "foo", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, &ResultSet{
+		Columns:   []ResultColumn{{Name: "id", Type: "int4"}},
+		Rows:      [][]interface{}{{int64(1)}},
+		Truncated: true,
+	}, res)
+}
+
+// syncClient serializes the calls to the mock, which isn't safe for concurrent use, so the race
+// detector only checks the API itself
+type syncClient struct {
+	mu sync.Mutex
+	*redshiftclientmock.MockRedshiftClient
+}
+
+func (s *syncClient) ListSchemasWithContext(ctx aws.Context, input *redshiftdataapiservice.ListSchemasInput, opts ...request.Option) (*redshiftdataapiservice.ListSchemasOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockRedshiftClient.ListSchemasWithContext(ctx, input, opts...)
+}
+
+func (s *syncClient) ExecuteStatementWithContext(ctx aws.Context, input *redshiftdataapiservice.ExecuteStatementInput, opts ...request.Option) (*redshiftdataapiservice.ExecuteStatementOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockRedshiftClient.ExecuteStatementWithContext(ctx, input, opts...)
+}
+
+func (s *syncClient) DescribeStatementWithContext(ctx aws.Context, input *redshiftdataapiservice.DescribeStatementInput, opts ...request.Option) (*redshiftdataapiservice.DescribeStatementOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockRedshiftClient.DescribeStatementWithContext(ctx, input, opts...)
+}
+
+// Test_ConcurrentCalls is meant to be run with the race detector, go test -race
+func Test_ConcurrentCalls(t *testing.T) {
+	client := &syncClient{MockRedshiftClient: &redshiftclientmock.MockRedshiftClient{
+		Resources:               map[string]map[string][]string{"public": {}, "sales": {}},
+		ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+		DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished)},
+	}}
+	c := &API{
+		settings:   &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"},
+		DataClient: client,
+		cache:      newResourceCache(time.Minute),
+		secrets:    newSecretCache(time.Minute),
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			res, err := c.Schemas(context.Background(), sqlds.Options{})
+			if err == nil && len(res) != 2 {
+				err = fmt.Errorf("unexpected schemas %v", res)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			// A cancelable context tracks the statement while it's in flight
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			output, err := c.Execute(ctx, &api.ExecuteQueryInput{Query: "SELECT 1"})
+			if err == nil {
+				_, err = c.Status(ctx, output)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 0, c.inflight.len())
+}
+
+func Test_ClientToken(t *testing.T) {
+	newClient := func() *redshiftclientmock.MockRedshiftClient {
+		return &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			ThrottledCalls:  2,
+		}
+	}
+	settings := &models.RedshiftDataSourceSettings{RetryDelay: 1}
+
+	t.Run("reuses the token across the retries of a submission", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: settings, DataClient: client}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Len(t, client.ClientTokens, 3)
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, client.ClientTokens[0])
+		assert.Equal(t, client.ClientTokens[0], client.ClientTokens[1])
+		assert.Equal(t, client.ClientTokens[0], client.ClientTokens[2])
+
+		_, err = c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, client.ClientTokens[0], client.ClientTokens[3])
+	})
+
+	t.Run("uses the token of the input", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: settings, DataClient: client}
+		_, err := c.ExecuteStatement(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}, ClientToken: "token"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"token", "token", "token"}, client.ClientTokens)
+	})
+
+	t.Run("sets a token for batches", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: settings, DataClient: client}
+		_, err := c.BatchExecute(context.TODO(), []string{"select 1", "select 2"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, aws.StringValue(client.BatchExecuteStatementInput.ClientToken))
+	})
+}
+
+func Test_Explain(t *testing.T) {
+	newClient := func() *redshiftclientmock.MockRedshiftClient {
+		plan := func(v string) []*redshiftdataapiservice.Field {
+			return []*redshiftdataapiservice.Field{{StringValue: aws.String(v)}}
+		}
+		return &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(true)},
+			Results: []*redshiftdataapiservice.GetStatementResultOutput{{
+				ColumnMetadata: []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("QUERY PLAN")}},
+				Records:        [][]*redshiftdataapiservice.Field{plan("XN Seq Scan on sales  (cost=0.00..1.72 rows=172 width=8)"), plan("  Filter: (qty > 1)")},
+			}},
+		}
+	}
+
+	t.Run("returns the plan", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: testSettings(), DataClient: client}
+		res, err := c.Explain(context.TODO(), " select * from sales where qty > 1;")
+		assert.NoError(t, err)
+		assert.Equal(t, "XN Seq Scan on sales  (cost=0.00..1.72 rows=172 width=8)\n  Filter: (qty > 1)", res)
+		assert.Equal(t, "EXPLAIN select * from sales where qty > 1", aws.StringValue(client.ExecuteStatementInput.Sql))
+	})
+
+	t.Run("runs EXPLAIN VERBOSE", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: testSettings(), DataClient: client}
+		_, err := c.ExplainVerbose(context.TODO(), "CREATE TABLE foo AS SELECT 1")
+		assert.NoError(t, err)
+		assert.Equal(t, "EXPLAIN VERBOSE CREATE TABLE foo AS SELECT 1", aws.StringValue(client.ExecuteStatementInput.Sql))
+	})
+
+	t.Run("rejects the statements without a plan", func(t *testing.T) {
+		for _, query := range []string{"CREATE TABLE foo (id int)", "DROP TABLE foo", "VACUUM", "select 1; select 2"} {
+			client := newClient()
+			c := &API{settings: testSettings(), DataClient: client}
+			_, err := c.Explain(context.TODO(), query)
+			assert.ErrorIs(t, err, NotExplainableError, query)
+			assert.Nil(t, client.ExecuteStatementInput)
+		}
+	})
+}
+
+func Test_SecretKMSError(t *testing.T) {
+	tests := []struct {
+		description string
+		err         error
+		action      string
+	}{
+		{"decryption failure", awserr.New(secretsmanager.ErrCodeDecryptionFailure, "Secrets Manager can't decrypt the protected secret text using the provided KMS key.", nil), "kms:Decrypt"},
+		{"access to KMS denied", awserr.New("AccessDeniedException", "Access to KMS is not allowed", nil), "kms:Decrypt"},
+		{"access to the secret denied", awserr.New("AccessDeniedException", "not authorized to perform: secretsmanager:GetSecretValue", nil), "secretsmanager:GetSecretValue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{SecretsClient: &redshiftclientmock.MockRedshiftClient{SecretsError: tt.err}}
+			_, err := c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+			var permErr *PermissionError
+			assert.True(t, errors.As(err, &permErr))
+			assert.Equal(t, tt.action, permErr.Action)
+		})
+	}
+}
+
+func Test_ListUntaggedSecrets(t *testing.T) {
+	t.Run("lists only the tagged secrets by default", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{UntaggedSecrets: []string{"foo"}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, SecretsClient: client}
+		res, err := c.Secrets(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ManagedSecret{}, res)
+	})
+
+	t.Run("lists the untagged secrets if no secret is tagged", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{UntaggedSecrets: []string{"foo"}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{ListUntaggedSecrets: true}, SecretsClient: client}
+		res, err := c.Secrets(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ManagedSecret{{Name: "foo", ARN: "arn:foo", Untagged: true}}, res)
+	})
+
+	t.Run("keeps the tagged secrets if any", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Secrets: []string{"bar"}, UntaggedSecrets: []string{"foo"}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{ListUntaggedSecrets: true}, SecretsClient: client}
+		res, err := c.Secrets(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ManagedSecret{{Name: "bar", ARN: "arn:bar"}}, res)
+	})
+}
+
+func Test_SecretsRetries(t *testing.T) {
+	t.Run("retries throttled calls", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Secrets: []string{"foo"}, Secret: `{"username":"bar"}`, SecretsThrottledCalls: 2}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, SecretsClient: client}
+		res, err := c.Secrets(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ManagedSecret{{Name: "foo", ARN: "arn:foo"}}, res)
+
+		client.SecretsThrottledCalls = 2
+		secret, err := c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+		assert.NoError(t, err)
+		assert.Equal(t, "bar", secret.DBUser)
+		assert.Equal(t, 3, client.SecretCalls)
+	})
+
+	t.Run("waits for the Retry-After delay", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Secrets: []string{"foo"}, SecretsThrottledCalls: 1, RetryAfter: "1"}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, SecretsClient: client}
+		start := time.Now()
+		_, err := c.Secrets(context.TODO())
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Secrets: []string{"foo"}, SecretsThrottledCalls: 1, RetryAfter: "5"}
+		c := &API{settings: &models.RedshiftDataSourceSettings{RetryDelay: 1}, SecretsClient: client}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := c.Secrets(ctx)
+		assert.Error(t, err)
+		assert.Equal(t, 0, client.SecretsThrottledCalls)
+	})
+
+	t.Run("returns the throttling error once the retries are exhausted", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{SecretsThrottledCalls: 10, RetryAfter: "1"}
+		c := &API{settings: &models.RedshiftDataSourceSettings{MaxRetries: 1, RetryDelay: 1}, SecretsClient: client}
+		_, err := c.Secret(context.TODO(), sqlds.Options{"secretARN": "arn"})
+		assert.True(t, isThrottlingError(err))
+		var hint *retryAfterError
+		assert.False(t, errors.As(err, &hint))
+	})
+}
+
+func Test_retryDelay(t *testing.T) {
+	assert.Equal(t, time.Second, retryDelay(time.Second, 0))
+	assert.Equal(t, 2*time.Second, retryDelay(time.Second, 2*time.Second))
+	assert.Equal(t, maxRetryDelay, retryDelay(time.Second, time.Hour))
+}
+
+func Test_EstimateRowCount(t *testing.T) {
+	newClient := func(records [][]*redshiftdataapiservice.Field) *redshiftclientmock.MockRedshiftClient {
+		return &redshiftclientmock.MockRedshiftClient{
+			ExecutionResult:         &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")},
+			DescribeStatementOutput: &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(true)},
+			Results:                 []*redshiftdataapiservice.GetStatementResultOutput{{Records: records}},
+		}
+	}
+
+	t.Run("returns the estimate of the statistics", func(t *testing.T) {
+		client := newClient([][]*redshiftdataapiservice.Field{{{LongValue: aws.Int64(1234)}}})
+		c := &API{settings: testSettings(), DataClient: client, cache: newResourceCache(time.Minute)}
+		res, err := c.EstimateRowCount(context.TODO(), "", `"Sales"`)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1234), res)
+		assert.Equal(t, []*redshiftdataapiservice.SqlParameter{
+			{Name: aws.String("schema"), Value: aws.String("public")},
+			{Name: aws.String("table"), Value: aws.String("Sales")},
+		}, client.ExecuteStatementInput.Parameters)
+
+		// The estimate is cached
+		client.ExecuteStatementInput = nil
+		res, err = c.EstimateRowCount(context.TODO(), "public", "Sales")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1234), res)
+		assert.Nil(t, client.ExecuteStatementInput)
+		// The estimates are kept apart from the lists
+		assert.Empty(t, c.cache.entries)
+		assert.Len(t, c.cache.counts, 1)
+	})
+
+	t.Run("returns an unknown count for the tables without statistics", func(t *testing.T) {
+		c := &API{settings: testSettings(), DataClient: newClient(nil)}
+		res, err := c.EstimateRowCount(context.TODO(), "public", "empty")
+		assert.NoError(t, err)
+		assert.Equal(t, UnknownRowCount, res)
+	})
+
+	t.Run("returns an unknown count if the catalog can't be read", func(t *testing.T) {
+		client := newClient(nil)
+		client.DescribeStatementOutput = &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFailed), Error: aws.String("ERROR: permission denied for relation svv_table_info")}
+		c := &API{settings: testSettings(), DataClient: client}
+		res, err := c.EstimateRowCount(context.TODO(), "public", "sales")
+		assert.NoError(t, err)
+		assert.Equal(t, UnknownRowCount, res)
+	})
+
+	t.Run("returns the other errors", func(t *testing.T) {
+		client := newClient(nil)
+		client.DescribeStatementOutput = &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFailed), Error: aws.String("boom")}
+		c := &API{settings: testSettings(), DataClient: client}
+		_, err := c.EstimateRowCount(context.TODO(), "public", "sales")
+		assert.Error(t, err)
+	})
+}
+
+func Test_QuerySecretARN(t *testing.T) {
+	arn := "arn:aws:secretsmanager:us-east-1:123456789012:secret:sales-AbCdEf"
+	newClient := func() *redshiftclientmock.MockRedshiftClient {
+		return &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+	}
+
+	t.Run("uses the secret of the query arguments", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: &models.RedshiftDataSourceSettings{DBUser: "user", QuerySecretARN: arn}, DataClient: client}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, arn, aws.StringValue(client.ExecuteStatementInput.SecretArn))
+		assert.Nil(t, client.ExecuteStatementInput.DbUser)
+	})
+
+	t.Run("uses the secret of the statement", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: &models.RedshiftDataSourceSettings{UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "configured"}}, DataClient: client}
+		_, err := c.ExecuteStatement(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}, SecretARN: arn})
+		assert.NoError(t, err)
+		assert.Equal(t, arn, aws.StringValue(client.ExecuteStatementInput.SecretArn))
+
+		_, err = c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "configured", aws.StringValue(client.ExecuteStatementInput.SecretArn))
+	})
+
+	t.Run("rejects an invalid secret ARN", func(t *testing.T) {
+		client := newClient()
+		c := &API{settings: &models.RedshiftDataSourceSettings{DBUser: "user"}, DataClient: client}
+		_, err := c.ExecuteStatement(context.TODO(), &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "select 1"}, SecretARN: "sales"})
+		assert.ErrorIs(t, err, api.ExecuteError)
+		assert.Nil(t, client.ExecuteStatementInput)
+	})
+
+	t.Run("validates the secret of the query arguments", func(t *testing.T) {
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", QuerySecretARN: "sales"}}
+		assert.EqualError(t, c.CheckSettings(context.TODO()), `invalid secret ARN "sales", expected arn:aws:secretsmanager:<region>:<account>:secret:<name>`)
+
+		c.settings.QuerySecretARN = arn
+		assert.NoError(t, c.CheckSettings(context.TODO()))
+
+		c.settings.DisableManagedSecrets = true
+		assert.ErrorIs(t, c.CheckSettings(context.TODO()), ManagedSecretsDisabledError)
+	})
+}
+
+func Test_StatementLifecycle(t *testing.T) {
+	result := &fakeredshift.Result{
+		Columns: []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("id"), TypeName: aws.String("int4")}},
+		Records: [][]*redshiftdataapiservice.Field{{{LongValue: aws.Int64(1)}}, {{LongValue: aws.Int64(2)}}, {{LongValue: aws.Int64(3)}}},
+	}
+
+	t.Run("statement submitted, polled and fetched in pages", func(t *testing.T) {
+		client := &fakeredshift.Client{Result: result, PageSize: 2}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		output, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select id from foo"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{output.ID}, client.Submitted())
+
+		status, err := c.Status(context.Background(), output)
+		assert.NoError(t, err)
+		assert.Equal(t, redshiftdataapiservice.StatusStringSubmitted, status.State)
+		assert.False(t, status.Finished)
+
+		final, err := c.WaitOnQuery(context.Background(), output, time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, redshiftdataapiservice.StatusStringFinished, final.State)
+		assert.True(t, final.HasResultSet)
+		assert.Equal(t, int64(3), final.Stats.ResultRows)
+
+		res, err := c.GetResult(context.Background(), output.ID, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, result.Records, res.Records)
+		assert.Equal(t, 2, client.Statement(output.ID).ResultPages)
+		assert.Equal(t, "select id from foo", aws.StringValue(client.Statement(output.ID).Input.Sql))
+	})
+
+	t.Run("failed statement", func(t *testing.T) {
+		client := &fakeredshift.Client{States: []string{redshiftdataapiservice.StatusStringStarted, redshiftdataapiservice.StatusStringFailed}, Error: "relation \"foo\" does not exist"}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		output, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select id from foo"})
+		assert.NoError(t, err)
+		_, err = c.WaitOnQuery(context.Background(), output, time.Millisecond)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "relation \"foo\" does not exist")
+		assert.Equal(t, 2, client.Statement(output.ID).Describes)
+	})
+
+	t.Run("canceled statement", func(t *testing.T) {
+		client := &fakeredshift.Client{States: []string{redshiftdataapiservice.StatusStringStarted}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		output, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select id from foo"})
+		assert.NoError(t, err)
+		assert.NoError(t, c.Stop(output))
+		status, err := c.Status(context.Background(), output)
+		assert.NoError(t, err)
+		assert.Equal(t, redshiftdataapiservice.StatusStringAborted, status.State)
+		assert.True(t, status.Finished)
+		assert.True(t, client.Statement(output.ID).Canceled)
+	})
+
+	t.Run("unknown statement", func(t *testing.T) {
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &fakeredshift.Client{}}
+		_, err := c.GetResult(context.Background(), "foo", 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("secrets", func(t *testing.T) {
+		client := &fakeredshift.Client{Secrets: map[string]string{"foo": `{"dbClusterIdentifier":"cluster","username":"admin"}`}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, SecretsClient: client}
+		secret, err := c.Secret(context.Background(), sqlds.Options{"secretARN": "arn:foo"})
+		assert.NoError(t, err)
+		assert.Equal(t, "cluster", secret.ClusterIdentifier)
+		assert.Equal(t, "admin", secret.DBUser)
+	})
+}
+
+func Test_ClusterNotFound(t *testing.T) {
+	notFound := awserr.New(redshiftdataapiservice.ErrCodeValidationException, "Redshift endpoint doesn't exist in this region.", nil)
+
+	t.Run("execute with a cluster", func(t *testing.T) {
+		settings := &models.RedshiftDataSourceSettings{ClusterIdentifier: "my-clutser"}
+		settings.Region = "us-east-2"
+		c := &API{settings: settings, DataClient: &redshiftclientmock.MockRedshiftClient{ExecuteError: notFound}}
+		_, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+		var notFoundErr *ClusterNotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.Equal(t, "my-clutser", notFoundErr.Identifier)
+		assert.Equal(t, "us-east-2", notFoundErr.Region)
+		assert.False(t, notFoundErr.Serverless)
+		assert.ErrorIs(t, err, api.ExecuteError)
+		assert.Contains(t, err.Error(), `cluster "my-clutser" not found in us-east-2`)
+	})
+
+	t.Run("execute with a workgroup in the default region", func(t *testing.T) {
+		settings := &models.RedshiftDataSourceSettings{WorkgroupName: "my-workgroup"}
+		settings.DefaultRegion = "eu-west-1"
+		workgroupNotFound := awserr.New(redshiftdataapiservice.ErrCodeValidationException, "Workgroup my-workgroup not found.", nil)
+		c := &API{settings: settings, DataClient: &redshiftclientmock.MockRedshiftClient{ExecuteError: workgroupNotFound}}
+		_, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+		var notFoundErr *ClusterNotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.Equal(t, &ClusterNotFoundError{Identifier: "my-workgroup", Serverless: true, Region: "eu-west-1", Err: notFoundErr.Err}, notFoundErr)
+		assert.Contains(t, err.Error(), `workgroup "my-workgroup" not found in eu-west-1`)
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "my-clutser"}, DataClient: &redshiftclientmock.MockRedshiftClient{ListError: notFound}}
+		_, err := c.Databases(context.Background(), sqlds.Options{})
+		var notFoundErr *ClusterNotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.ErrorIs(t, err, ListDatabasesError)
+		assert.Contains(t, err.Error(), `cluster "my-clutser" not found in the default region`)
+
+		_, err = c.Schemas(context.Background(), sqlds.Options{})
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.ErrorIs(t, err, ListSchemasError)
+
+		_, err = c.Tables(context.Background(), sqlds.Options{"schema": "public"})
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.ErrorIs(t, err, ListTablesError)
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		for _, executeErr := range []error{
+			errors.New("boom"),
+			awserr.New(redshiftdataapiservice.ErrCodeValidationException, "relation \"cluster\" not found", nil),
+			awserr.New(redshiftdataapiservice.ErrCodeValidationException, "Cluster my-cluster is paused", nil),
+			awserr.New(redshiftdataapiservice.ErrCodeResourceNotFoundException, "Query does not exist.", nil),
+		} {
+			c := &API{settings: &models.RedshiftDataSourceSettings{ClusterIdentifier: "my-cluster", MaxRetries: -1}, DataClient: &redshiftclientmock.MockRedshiftClient{ExecuteError: executeErr}}
+			_, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+			var notFoundErr *ClusterNotFoundError
+			assert.False(t, errors.As(err, &notFoundErr), executeErr.Error())
+			assert.ErrorIs(t, err, api.ExecuteError)
+		}
+	})
+}
+
+func Test_CanceledStatement(t *testing.T) {
+	t.Run("stopped statement", func(t *testing.T) {
+		client := &fakeredshift.Client{States: []string{redshiftdataapiservice.StatusStringStarted}, Result: &fakeredshift.Result{}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		output, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.NoError(t, c.StopWithContext(context.Background(), output))
+		status, err := c.WaitOnQuery(context.Background(), output, time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, PhaseCanceled, status.Phase)
+		assert.True(t, status.Finished)
+		assert.False(t, status.HasResultSet)
+	})
+
+	t.Run("unexpected abort", func(t *testing.T) {
+		client := &fakeredshift.Client{States: []string{redshiftdataapiservice.StatusStringAborted}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		output, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		status, err := c.StatementStatus(context.Background(), output)
+		var statementErr *StatementError
+		assert.True(t, errors.As(err, &statementErr))
+		assert.Equal(t, redshiftdataapiservice.StatusStringAborted, statementErr.State)
+		assert.Equal(t, PhaseFailed, status.Phase)
+	})
+
+	t.Run("failed cancel", func(t *testing.T) {
+		client := &fakeredshift.Client{}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		err := c.StopWithContext(context.Background(), &api.ExecuteQueryOutput{ID: "foo"})
+		assert.ErrorIs(t, err, api.StopError)
+		assert.False(t, c.canceled.has("foo"))
+	})
+}
+
+func Test_ServerlessAuth(t *testing.T) {
+	t.Run("settings", func(t *testing.T) {
+		tests := []struct {
+			description string
+			settings    *models.RedshiftDataSourceSettings
+			err         error
+		}{
+			{"IAM identity", &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db"}, nil},
+			{"managed secret", &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db", UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}}, nil},
+			{"managed secret with a database user", &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db", DBUser: "user", UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn"}}, nil},
+			{"query secret", &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db", DBUser: "user", QuerySecretARN: "arn:aws:secretsmanager:us-east-1:123456789012:secret:foo"}, nil},
+			{"database user", &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db", DBUser: "user"}, UnsupportedAuthError},
+		}
+		for _, tt := range tests {
+			t.Run(tt.description, func(t *testing.T) {
+				err := validateSettings(tt.settings)
+				if tt.err == nil {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, tt.err)
+				}
+			})
+		}
+	})
+
+	t.Run("statements", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db"}, DataClient: client}
+		_, err := c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "workgroup", aws.StringValue(client.ExecuteStatementInput.WorkgroupName))
+		assert.Nil(t, client.ExecuteStatementInput.ClusterIdentifier)
+		assert.Nil(t, client.ExecuteStatementInput.DbUser)
+		assert.Nil(t, client.ExecuteStatementInput.SecretArn)
+
+		c.settings.UseManagedSecret, c.settings.ManagedSecret.ARN = true, "arn:secret"
+		_, err = c.Execute(context.TODO(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "arn:secret", aws.StringValue(client.ExecuteStatementInput.SecretArn))
+		assert.Nil(t, client.ExecuteStatementInput.DbUser)
+	})
+
+	t.Run("no cluster credentials", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{}
+		c := &API{settings: &models.RedshiftDataSourceSettings{WorkgroupName: "workgroup", Database: "db", DBUser: "user"}, ManagementClient: client}
+		_, err := c.ClusterCredentials(context.TODO(), 0)
+		assert.ErrorIs(t, err, UnsupportedAuthError)
+		assert.Nil(t, client.ClusterCredentialsInput)
+	})
+}
+
+func Test_pollOptions(t *testing.T) {
+	tests := []struct {
+		description string
+		settings    *models.RedshiftDataSourceSettings
+		options     PollOptions
+		expected    PollOptions
+	}{
+		{"defaults", &models.RedshiftDataSourceSettings{}, PollOptions{}, PollOptions{Min: defaultPollInterval, Max: maxPollInterval, Factor: defaultPollFactor}},
+		{"settings", &models.RedshiftDataSourceSettings{PollInterval: 50, MaxPollInterval: 10000, PollFactor: 3}, PollOptions{}, PollOptions{Min: 50 * time.Millisecond, Max: 10 * time.Second, Factor: 3}},
+		{"options over settings", &models.RedshiftDataSourceSettings{PollInterval: 50, MaxPollInterval: 10000}, PollOptions{Min: time.Second, Factor: 1.2}, PollOptions{Min: time.Second, Max: 10 * time.Second, Factor: 1.2}},
+		{"maximum below the minimum", &models.RedshiftDataSourceSettings{MaxPollInterval: 50}, PollOptions{Min: time.Second}, PollOptions{Min: time.Second, Max: time.Second, Factor: defaultPollFactor}},
+		{"shrinking factor", &models.RedshiftDataSourceSettings{}, PollOptions{Factor: 0.5}, PollOptions{Min: defaultPollInterval, Max: maxPollInterval, Factor: defaultPollFactor}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &API{settings: tt.settings}
+			assert.Equal(t, tt.expected, c.pollOptions(tt.options))
+		})
+	}
+
+	t.Run("invalid settings", func(t *testing.T) {
+		settings := &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user", PollFactor: 0.5}
+		assert.EqualError(t, validateSettings(settings), "invalid poll factor 0.5, it must be at least 1")
+		settings.PollFactor, settings.PollInterval, settings.MaxPollInterval = 0, 1000, 100
+		assert.EqualError(t, validateSettings(settings), "invalid poll interval, 1000ms is longer than the maximum of 100ms")
+	})
+}
+
+func Test_WaitOnQueryWithPolling(t *testing.T) {
+	t.Run("polls quickly then backs off", func(t *testing.T) {
+		client := &fakeredshift.Client{States: []string{
+			redshiftdataapiservice.StatusStringSubmitted,
+			redshiftdataapiservice.StatusStringStarted,
+			redshiftdataapiservice.StatusStringStarted,
+			redshiftdataapiservice.StatusStringFinished,
+		}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		output, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		status, err := c.WaitOnQueryWithPolling(context.Background(), output, PollOptions{Min: time.Millisecond, Max: 5 * time.Millisecond, Factor: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, redshiftdataapiservice.StatusStringFinished, status.State)
+		assert.Equal(t, 4, client.Statement(output.ID).Describes)
+	})
+
+	t.Run("cancel interrupts the wait", func(t *testing.T) {
+		client := &fakeredshift.Client{States: []string{redshiftdataapiservice.StatusStringStarted}}
+		c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: client}
+		output, err := c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		start := time.Now()
+		_, err = c.WaitOnQueryWithPolling(ctx, output, PollOptions{Min: time.Hour, Max: time.Hour})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+		assert.Equal(t, 1, client.Statement(output.ID).Describes)
+		assert.True(t, client.Statement(output.ID).Canceled)
+	})
+}
+
+func Test_Overrides(t *testing.T) {
+	settings := func() *models.RedshiftDataSourceSettings {
+		return &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"}
+	}
+
+	t.Run("statements use the overrides", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{ExecutionResult: &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}}
+		c := &API{settings: settings(), DataClient: client}
+		ctx, err := c.WithOverrides(context.Background(), Overrides{ClusterIdentifier: "tenant", Database: "tenant_db", SecretARN: "arn:aws:secretsmanager:us-east-1:123456789012:secret:tenant"})
+		assert.NoError(t, err)
+		_, err = c.Execute(ctx, &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "tenant", aws.StringValue(client.ExecuteStatementInput.ClusterIdentifier))
+		assert.Equal(t, "tenant_db", aws.StringValue(client.ExecuteStatementInput.Database))
+		assert.Equal(t, "arn:aws:secretsmanager:us-east-1:123456789012:secret:tenant", aws.StringValue(client.ExecuteStatementInput.SecretArn))
+		assert.Nil(t, client.ExecuteStatementInput.DbUser)
+
+		// The settings are unchanged
+		assert.Equal(t, settings(), c.settings)
+		_, err = c.Execute(context.Background(), &api.ExecuteQueryInput{Query: "select 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "cluster", aws.StringValue(client.ExecuteStatementInput.ClusterIdentifier))
+		assert.Equal(t, "user", aws.StringValue(client.ExecuteStatementInput.DbUser))
+	})
+
+	t.Run("lists use the overrides and their own cache", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{Databases: []string{"foo"}}
+		c := &API{settings: settings(), DataClient: client, cache: newResourceCache(time.Minute)}
+		ctx, err := c.WithOverrides(context.Background(), Overrides{WorkgroupName: "workgroup", SecretARN: "arn:aws:secretsmanager:us-east-1:123456789012:secret:tenant"})
+		assert.NoError(t, err)
+		_, err = c.Databases(ctx, sqlds.Options{})
+		assert.NoError(t, err)
+		assert.NotEqual(t, c.cacheKey(context.Background(), "schemas"), c.cacheKey(ctx, "schemas"))
+		assert.Equal(t, apiInput{
+			WorkgroupName: aws.String("workgroup"),
+			Database:      aws.String("db"),
+			SecretARN:     aws.String("arn:aws:secretsmanager:us-east-1:123456789012:secret:tenant"),
+		}, c.apiInput(ctx))
+	})
+
+	t.Run("invalid overrides", func(t *testing.T) {
+		c := &API{settings: settings()}
+		_, err := c.WithOverrides(context.Background(), Overrides{ClusterIdentifier: "foo", WorkgroupName: "bar"})
+		assert.EqualError(t, err, "cluster identifier and workgroup name overrides are mutually exclusive, only one of them can be set")
+		_, err = c.WithOverrides(context.Background(), Overrides{SecretARN: "foo"})
+		assert.Error(t, err)
+		// The database user of the settings can't be used with a workgroup
+		_, err = c.WithOverrides(context.Background(), Overrides{WorkgroupName: "bar"})
+		assert.ErrorIs(t, err, UnsupportedAuthError)
+
+		c.settings.DisableManagedSecrets = true
+		_, err = c.WithOverrides(context.Background(), Overrides{SecretARN: "arn:aws:secretsmanager:us-east-1:123456789012:secret:tenant"})
+		assert.ErrorIs(t, err, ManagedSecretsDisabledError)
+	})
+
+	t.Run("concurrent overrides", func(t *testing.T) {
+		client := &fakeredshift.Client{}
+		c := &API{settings: settings(), DataClient: client}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ctx, err := c.WithOverrides(context.Background(), Overrides{ClusterIdentifier: fmt.Sprintf("tenant-%d", i)})
+				assert.NoError(t, err)
+				_, err = c.Execute(ctx, &api.ExecuteQueryInput{Query: fmt.Sprintf("select %d", i)})
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+		submitted := client.Submitted()
+		assert.Len(t, submitted, 10)
+		for _, id := range submitted {
+			input := client.Statement(id).Input
+			assert.Equal(t, "select "+strings.TrimPrefix(aws.StringValue(input.ClusterIdentifier), "tenant-"), aws.StringValue(input.Sql))
+		}
+	})
+}
+
+func Test_SyntaxError(t *testing.T) {
+	tests := []struct {
+		description string
+		message     string
+		query       string
+		expected    *SyntaxError
+	}{
+		{
+			description: "near a token",
+			message:     `ERROR: syntax error at or near "form" Position: 10`,
+			query:       "select * form foo",
+			expected:    &SyntaxError{Line: 1, Column: 10, Position: 10, Near: "form"},
+		},
+		{
+			description: "on another line",
+			message:     "ERROR: syntax error at or near \"whre\"\n  Position: 19 [ErrorId: 1-6411d11f-0a2b3c4d5e6f]",
+			query:       "select *\nfrom foo\nwhre id = 1",
+			expected:    &SyntaxError{Line: 3, Column: 1, Position: 19, Near: "whre"},
+		},
+		{
+			description: "at the end of the input",
+			message:     "ERROR: syntax error at end of input\n  Position: 14",
+			query:       "select * from",
+			expected:    &SyntaxError{Line: 1, Column: 14, Position: 14},
+		},
+		{
+			description: "with a quote in the token",
+			message:     `ERROR: syntax error at or near """" Position: 8`,
+			query:       `select "" from foo`,
+			expected:    &SyntaxError{Line: 1, Column: 8, Position: 8, Near: `"`},
+		},
+		{
+			description: "multibyte characters",
+			message:     `ERROR: syntax error at or near "form" Position: 15`,
+			query:       "select 'é',\n* form foo",
+			expected:    &SyntaxError{Line: 2, Column: 3, Position: 15, Near: "form"},
+		},
+		{
+			description: "unknown query",
+			message:     `ERROR: syntax error at or near "form" Position: 10`,
+			expected:    &SyntaxError{Position: 10, Near: "form"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			status := &redshiftdataapiservice.DescribeStatementOutput{Status: aws.String("FAILED"), Error: aws.String(tt.message)}
+			if tt.query != "" {
+				status.QueryString = aws.String(tt.query)
 			}
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{DescribeStatementOutput: status}}
+			_, err := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+			var syntaxErr *SyntaxError
+			assert.True(t, errors.As(err, &syntaxErr))
+			tt.expected.StatementError = syntaxErr.StatementError
+			assert.Equal(t, tt.expected, syntaxErr)
+			assert.Equal(t, tt.message, err.Error())
+			var statementErr *StatementError
+			assert.True(t, errors.As(err, &statementErr))
 		})
 	}
+
+	t.Run("falls back to the statement error", func(t *testing.T) {
+		for _, message := range []string{
+			`ERROR: relation "foo" does not exist`,
+			`ERROR: syntax error at or near "form"`,
+			`ERROR: syntax error at or near "form" Position: 0`,
+		} {
+			status := &redshiftdataapiservice.DescribeStatementOutput{Status: aws.String("FAILED"), Error: aws.String(message), QueryString: aws.String("select * form foo")}
+			c := &API{settings: &models.RedshiftDataSourceSettings{}, DataClient: &redshiftclientmock.MockRedshiftClient{DescribeStatementOutput: status}}
+			_, err := c.StatementStatus(context.TODO(), &api.ExecuteQueryOutput{ID: "foo"})
+			var syntaxErr *SyntaxError
+			assert.False(t, errors.As(err, &syntaxErr), message)
+			assert.EqualError(t, err, message)
+		}
+	})
+}
+
+func Test_HealthCheckSecret(t *testing.T) {
+	settings := func() *models.RedshiftDataSourceSettings {
+		return &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", UseManagedSecret: true, ManagedSecret: models.ManagedSecret{ARN: "arn:secret"}}
+	}
+	stage := func(err error) HealthCheckStage {
+		var healthErr *HealthCheckError
+		if !errors.As(err, &healthErr) {
+			return ""
+		}
+		return healthErr.Stage
+	}
+
+	t.Run("readable secret", func(t *testing.T) {
+		client := &fakeredshift.Client{Secrets: map[string]string{"secret": `{"dbClusterIdentifier":"cluster","username":"admin"}`}}
+		c := &API{settings: settings(), DataClient: client, SecretsClient: client, secrets: newSecretCache(time.Minute)}
+		assert.NoError(t, c.HealthCheck(context.Background()))
+		assert.Len(t, client.Submitted(), 1)
+	})
+
+	t.Run("unreadable secret", func(t *testing.T) {
+		client := &redshiftclientmock.MockRedshiftClient{SecretsError: awserr.New("AccessDeniedException", "not authorized to perform: secretsmanager:GetSecretValue", nil)}
+		c := &API{settings: settings(), DataClient: client, SecretsClient: client}
+		err := c.HealthCheck(context.Background())
+		assert.Equal(t, HealthCheckSecretUnreadable, stage(err))
+		var permissionErr *PermissionError
+		assert.True(t, errors.As(err, &permissionErr))
+		assert.Nil(t, client.ExecuteStatementInput)
+	})
+
+	t.Run("malformed secret", func(t *testing.T) {
+		client := &fakeredshift.Client{Secrets: map[string]string{"secret": `{"password":"admin"}`}}
+		c := &API{settings: settings(), DataClient: client, SecretsClient: client}
+		err := c.HealthCheck(context.Background())
+		assert.Equal(t, HealthCheckSecretMalformed, stage(err))
+		assert.ErrorIs(t, err, InvalidSecretError)
+		assert.Empty(t, client.Submitted())
+	})
+
+	t.Run("secret of another cluster", func(t *testing.T) {
+		client := &fakeredshift.Client{Secrets: map[string]string{"secret": `{"dbClusterIdentifier":"other","username":"admin"}`}}
+		c := &API{settings: settings(), DataClient: client, SecretsClient: client}
+		err := c.HealthCheck(context.Background())
+		assert.Equal(t, HealthCheckSecretMalformed, stage(err))
+		assert.EqualError(t, err, "health check failed, the managed secret arn:secret is for the cluster other, not cluster")
+	})
+
+	t.Run("read again instead of cached", func(t *testing.T) {
+		client := &fakeredshift.Client{Secrets: map[string]string{"secret": `{"password":"admin"}`}}
+		c := &API{settings: settings(), DataClient: client, SecretsClient: client, secrets: newSecretCache(time.Minute)}
+		c.secrets.set("arn:secret", "v0", &models.RedshiftSecret{ClusterIdentifier: "cluster", DBUser: "admin"})
+		assert.Equal(t, HealthCheckSecretMalformed, stage(c.HealthCheck(context.Background())))
+	})
+
+	t.Run("query failed", func(t *testing.T) {
+		client := &fakeredshift.Client{States: []string{redshiftdataapiservice.StatusStringFailed}, Error: "boom", Secrets: map[string]string{"secret": `{"username":"admin"}`}}
+		c := &API{settings: settings(), DataClient: client, SecretsClient: client}
+		err := c.HealthCheck(context.Background())
+		assert.Equal(t, HealthCheckQueryFailed, stage(err))
+		assert.EqualError(t, err, "health check failed: boom")
+	})
+}
+
+// blockingLister counts the calls to ListSchemas, which wait until release is closed or their context is done
+type blockingLister struct {
+	*redshiftclientmock.MockRedshiftClient
+	mu      sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (b *blockingLister) ListSchemasWithContext(ctx aws.Context, input *redshiftdataapiservice.ListSchemasInput, opts ...request.Option) (*redshiftdataapiservice.ListSchemasOutput, error) {
+	b.mu.Lock()
+	b.calls++
+	b.mu.Unlock()
+	b.started <- struct{}{}
+	select {
+	case <-ctx.Done():
+		return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+	case <-b.release:
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &redshiftdataapiservice.ListSchemasOutput{Schemas: aws.StringSlice([]string{"public", "sales"})}, nil
+}
+
+func (b *blockingLister) callCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+func Test_CoalescedLists(t *testing.T) {
+	settings := func() *models.RedshiftDataSourceSettings {
+		return &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"}
+	}
+	// schemas lists the schemas in n goroutines once a first call is in flight
+	schemas := func(c *API, ctx context.Context, n int) ([][]string, []error) {
+		results, errs := make([][]string, n), make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = c.Schemas(ctx, sqlds.Options{})
+			}(i)
+		}
+		wg.Wait()
+		return results, errs
+	}
+
+	t.Run("concurrent calls share one call", func(t *testing.T) {
+		lister := &blockingLister{MockRedshiftClient: &redshiftclientmock.MockRedshiftClient{}, started: make(chan struct{}, 10), release: make(chan struct{})}
+		c := &API{settings: settings(), DataClient: lister}
+		go func() {
+			<-lister.started
+			// Let the other callers join the call in flight
+			time.Sleep(20 * time.Millisecond)
+			close(lister.release)
+		}()
+		results, errs := schemas(c, context.Background(), 5)
+		assert.Equal(t, 1, lister.callCount())
+		for i := range results {
+			assert.NoError(t, errs[i])
+			assert.Equal(t, []string{"public", "sales"}, results[i])
+		}
+		results[0][0] = "modified"
+		assert.Equal(t, "public", results[1][0])
+	})
+
+	t.Run("errors are returned to every caller", func(t *testing.T) {
+		lister := &blockingLister{MockRedshiftClient: &redshiftclientmock.MockRedshiftClient{}, started: make(chan struct{}, 10), release: make(chan struct{}), err: errors.New("boom")}
+		c := &API{settings: settings(), DataClient: lister}
+		go func() {
+			<-lister.started
+			time.Sleep(20 * time.Millisecond)
+			close(lister.release)
+		}()
+		results, errs := schemas(c, context.Background(), 5)
+		assert.Equal(t, 1, lister.callCount())
+		for i := range results {
+			assert.ErrorIs(t, errs[i], ListSchemasError)
+			assert.Nil(t, results[i])
+		}
+	})
+
+	t.Run("different credentials don't share a call", func(t *testing.T) {
+		lister := &blockingLister{MockRedshiftClient: &redshiftclientmock.MockRedshiftClient{}, started: make(chan struct{}, 10), release: make(chan struct{})}
+		c := &API{settings: settings(), DataClient: lister}
+		ctx, err := c.WithOverrides(context.Background(), Overrides{SecretARN: "arn:aws:secretsmanager:us-east-1:123456789012:secret:tenant"})
+		assert.NoError(t, err)
+		go func() {
+			<-lister.started
+			<-lister.started
+			close(lister.release)
+		}()
+		var wg sync.WaitGroup
+		for _, ctx := range []context.Context{context.Background(), ctx} {
+			wg.Add(1)
+			go func(ctx context.Context) {
+				defer wg.Done()
+				_, err := c.Schemas(ctx, sqlds.Options{})
+				assert.NoError(t, err)
+			}(ctx)
+		}
+		wg.Wait()
+		assert.Equal(t, 2, lister.callCount())
+	})
+
+	t.Run("a canceled caller doesn't fail the others", func(t *testing.T) {
+		lister := &blockingLister{MockRedshiftClient: &redshiftclientmock.MockRedshiftClient{}, started: make(chan struct{}, 10), release: make(chan struct{})}
+		c := &API{settings: settings(), DataClient: lister}
+		ctx, cancel := context.WithCancel(context.Background())
+		first := make(chan error)
+		go func() {
+			_, err := c.Schemas(ctx, sqlds.Options{})
+			first <- err
+		}()
+		<-lister.started
+		second := make(chan error)
+		go func() {
+			res, err := c.Schemas(context.Background(), sqlds.Options{})
+			assert.Equal(t, []string{"public", "sales"}, res)
+			second <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-first, CanceledError)
+		// The waiting caller makes the call again
+		<-lister.started
+		close(lister.release)
+		assert.NoError(t, <-second)
+		assert.Equal(t, 2, lister.callCount())
+	})
+
+	t.Run("a caller past its deadline doesn't fail the others", func(t *testing.T) {
+		lister := &blockingLister{MockRedshiftClient: &redshiftclientmock.MockRedshiftClient{}, started: make(chan struct{}, 10), release: make(chan struct{})}
+		c := &API{settings: settings(), DataClient: lister}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		first := make(chan error)
+		go func() {
+			_, err := c.Schemas(ctx, sqlds.Options{})
+			first <- err
+		}()
+		<-lister.started
+		second := make(chan error)
+		go func() {
+			res, err := c.Schemas(context.Background(), sqlds.Options{})
+			assert.Equal(t, []string{"public", "sales"}, res)
+			second <- err
+		}()
+		assert.Error(t, <-first)
+		// The waiting caller makes the call again instead of getting the deadline of the first one
+		select {
+		case <-lister.started:
+		case err := <-second:
+			t.Fatalf("the waiting caller got the error of the first one: %v", err)
+		}
+		close(lister.release)
+		assert.NoError(t, <-second)
+		assert.Equal(t, 2, lister.callCount())
+	})
+}
+
+func Test_NewResultSetIntegers(t *testing.T) {
+	// 2^53 + 1 is rounded to 2^53 as a float64
+	const bigint int64 = 9007199254740993
+	columns := []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("id"), TypeName: aws.String("int8")},
+		{Name: aws.String("count"), TypeName: aws.String("int4")},
+		{Name: aws.String("ratio"), TypeName: aws.String("float8")},
+	}
+	res, err := NewResultSet(columns, [][]*redshiftdataapiservice.Field{
+		{{LongValue: aws.Int64(bigint)}, {StringValue: aws.String("42")}, {DoubleValue: aws.Float64(0.5)}},
+		{{StringValue: aws.String("9007199254740993")}, {IsNull: aws.Bool(true)}, {IsNull: aws.Bool(true)}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{bigint, int64(42), 0.5}, {bigint, nil, nil}}, res.Rows)
+
+	_, err = NewResultSet(columns[:1], [][]*redshiftdataapiservice.Field{{{StringValue: aws.String("1.5")}}})
+	assert.ErrorIs(t, err, ResultError)
+	_, err = NewResultSet(columns[:1], [][]*redshiftdataapiservice.Field{{{DoubleValue: aws.Float64(1)}}})
+	assert.ErrorIs(t, err, ResultError)
 }
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/redshift-datasource/pkg/redshift/models"
+)
+
+const defaultCacheTTL = 5 * time.Minute
+
+type cacheEntry struct {
+	value   []string
+	expires time.Time
+}
+
+type countEntry struct {
+	value   int64
+	expires time.Time
+}
+
+// resourceCache stores resource lists (e.g. schemas or tables) and the row counts of the tables for a limited time.
+// It's safe for concurrent use and a nil cache never stores anything.
+type resourceCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	counts  map[string]countEntry
+}
+
+func newResourceCache(ttl time.Duration) *resourceCache {
+	return &resourceCache{ttl: ttl, entries: map[string]cacheEntry{}, counts: map[string]countEntry{}}
+}
+
+func (c *resourceCache) get(key string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return append([]string{}, entry.value...), true
+}
+
+func (c *resourceCache) set(key string, value []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: append([]string{}, value...), expires: time.Now().Add(c.ttl)}
+}
+
+func (c *resourceCache) getCount(key string) (int64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.counts[key]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.counts, key)
+		return 0, false
+	}
+	return entry.value, true
+}
+
+func (c *resourceCache) setCount(key string, value int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key] = countEntry{value: value, expires: time.Now().Add(c.ttl)}
+}
+
+func (c *resourceCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]cacheEntry{}
+	c.counts = map[string]countEntry{}
+}
+
+const defaultSecretCacheTTL = time.Minute
+
+type secretEntry struct {
+	secret    models.RedshiftSecret
+	versionID string
+	expires   time.Time
+}
+
+// secretCache stores parsed managed secrets by ARN for a limited time.
+// It's safe for concurrent use and a nil cache never stores anything.
+type secretCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]secretEntry
+}
+
+func newSecretCache(ttl time.Duration) *secretCache {
+	return &secretCache{ttl: ttl, entries: map[string]secretEntry{}}
+}
+
+func (c *secretCache) get(arn string) (*models.RedshiftSecret, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[arn]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	secret := entry.secret
+	return &secret, true
+}
+
+// set stores the secret and returns true if it replaces a different version of it, meaning it was rotated
+func (c *secretCache) set(arn string, versionID string, secret *models.RedshiftSecret) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, existed := c.entries[arn]
+	c.entries[arn] = secretEntry{secret: *secret, versionID: versionID, expires: time.Now().Add(c.ttl)}
+	return existed && previous.versionID != versionID
+}
+
+func (c *secretCache) invalidate(arn string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, arn)
+}
+
+func (c *secretCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]secretEntry{}
+}
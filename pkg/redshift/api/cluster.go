@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshift"
+	"github.com/aws/aws-sdk-go/service/redshiftserverless"
+	"github.com/grafana/redshift-datasource/pkg/redshift/models"
+)
+
+// ClusterInfo describes the cluster, or the Serverless workgroup, of the data source
+type ClusterInfo struct {
+	// Identifier is the cluster identifier or the workgroup name
+	Identifier string
+	Status     string
+	Endpoint   models.RedshiftEndpoint
+	// NodeType and NumberOfNodes are only set for provisioned clusters
+	NodeType      string
+	NumberOfNodes int64
+	// Serverless is true for a workgroup, BaseCapacity is its base capacity in Redshift Processing Units
+	Serverless   bool
+	BaseCapacity int64
+}
+
+// DescribeCluster returns the node type, status and endpoint of the configured cluster. It needs the
+// redshift:DescribeClusters permission, or redshift-serverless:GetWorkgroup for a Serverless workgroup.
+func (c *API) DescribeCluster(ctx aws.Context) (*ClusterInfo, error) {
+	settings := c.overriddenSettings(ctx)
+	if settings.WorkgroupName != "" {
+		return c.describeWorkgroup(ctx)
+	}
+	if settings.ClusterIdentifier == "" {
+		return nil, fmt.Errorf("missing cluster identifier")
+	}
+	c.metrics().IncCall("DescribeClusters")
+	var out *redshift.DescribeClustersOutput
+	err := c.withTimeout(ctx, "DescribeClusters", func(ctx aws.Context) (err error) {
+		out, err = c.ManagementClient.DescribeClustersWithContext(ctx, &redshift.DescribeClustersInput{
+			ClusterIdentifier: aws.String(settings.ClusterIdentifier),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, permissionError(err, fmt.Errorf("error describing cluster %s: %w", settings.ClusterIdentifier, err))
+	}
+	if out == nil || len(out.Clusters) == 0 || out.Clusters[0] == nil {
+		return nil, fmt.Errorf("cluster %s not found", settings.ClusterIdentifier)
+	}
+	cluster := out.Clusters[0]
+	res := &ClusterInfo{
+		Identifier:    aws.StringValue(cluster.ClusterIdentifier),
+		Status:        aws.StringValue(cluster.ClusterStatus),
+		NodeType:      aws.StringValue(cluster.NodeType),
+		NumberOfNodes: aws.Int64Value(cluster.NumberOfNodes),
+	}
+	// A cluster being created has no endpoint yet
+	if cluster.Endpoint != nil {
+		res.Endpoint = models.RedshiftEndpoint{
+			Address: aws.StringValue(cluster.Endpoint.Address),
+			Port:    aws.Int64Value(cluster.Endpoint.Port),
+		}
+	}
+	return res, nil
+}
+
+func (c *API) describeWorkgroup(ctx aws.Context) (*ClusterInfo, error) {
+	settings := c.overriddenSettings(ctx)
+	if c.ServerlessClient == nil {
+		return nil, fmt.Errorf("missing Redshift Serverless client")
+	}
+	c.metrics().IncCall("GetWorkgroup")
+	var out *redshiftserverless.GetWorkgroupOutput
+	err := c.withTimeout(ctx, "GetWorkgroup", func(ctx aws.Context) (err error) {
+		out, err = c.ServerlessClient.GetWorkgroupWithContext(ctx, &redshiftserverless.GetWorkgroupInput{
+			WorkgroupName: aws.String(settings.WorkgroupName),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, permissionError(err, fmt.Errorf("error describing workgroup %s: %w", settings.WorkgroupName, err))
+	}
+	if out == nil || out.Workgroup == nil {
+		return nil, fmt.Errorf("workgroup %s not found", settings.WorkgroupName)
+	}
+	workgroup := out.Workgroup
+	res := &ClusterInfo{
+		Identifier:   aws.StringValue(workgroup.WorkgroupName),
+		Status:       aws.StringValue(workgroup.Status),
+		Serverless:   true,
+		BaseCapacity: aws.Int64Value(workgroup.BaseCapacity),
+	}
+	if workgroup.Endpoint != nil {
+		res.Endpoint = models.RedshiftEndpoint{
+			Address: aws.StringValue(workgroup.Endpoint.Address),
+			Port:    aws.Int64Value(workgroup.Endpoint.Port),
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/sql/api"
+)
+
+var (
+	iamRoleARNRegexp = regexp.MustCompile(`^arn:aws[\w-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+	s3PathRegexp     = regexp.MustCompile(`^s3://[a-z0-9][a-z0-9.-]{1,61}[a-z0-9](/.*)?$`)
+)
+
+// CopyOptions describe a COPY of files from S3 into a table
+type CopyOptions struct {
+	// Schema is optional, the table is looked up in the search path if empty
+	Schema string
+	Table  string
+	// Columns are optional, the files must have every column of the table otherwise
+	Columns []string
+	// S3Path is the prefix of the files to load, e.g. s3://bucket/data/
+	S3Path string
+	// IAMRole is the ARN of the role the cluster assumes to read the files
+	IAMRole string
+	// Format is one of CSV, JSON, PARQUET, ORC or AVRO. Pipe delimited text is expected if empty.
+	Format string
+	// Region of the bucket, needed when it's not the region of the cluster
+	Region string
+}
+
+// UnloadOptions describe an UNLOAD of a query result to files in S3
+type UnloadOptions struct {
+	Query string
+	// S3Path is the prefix of the files to write, e.g. s3://bucket/export/
+	S3Path string
+	// IAMRole is the ARN of the role the cluster assumes to write the files
+	IAMRole string
+	// Format is one of CSV, JSON or PARQUET. Pipe delimited text is written if empty.
+	Format string
+}
+
+var (
+	copyFormats   = map[string]string{"CSV": "CSV", "JSON": "JSON 'auto'", "PARQUET": "PARQUET", "ORC": "ORC", "AVRO": "AVRO 'auto'"}
+	unloadFormats = map[string]string{"CSV": "CSV", "JSON": "JSON", "PARQUET": "PARQUET"}
+)
+
+// Copy loads files from S3 into a table, the returned ID identifies the COPY statement
+func (c *API) Copy(ctx context.Context, options CopyOptions) (*api.ExecuteQueryOutput, error) {
+	query, err := copyQuery(options)
+	if err != nil {
+		return nil, err
+	}
+	return c.ExecuteStatement(ctx, &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: query}})
+}
+
+// Unload writes the result of a query to files in S3, the returned ID identifies the UNLOAD statement
+func (c *API) Unload(ctx context.Context, options UnloadOptions) (*api.ExecuteQueryOutput, error) {
+	query, err := unloadQuery(options)
+	if err != nil {
+		return nil, err
+	}
+	return c.ExecuteStatement(ctx, &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: query}})
+}
+
+func copyQuery(options CopyOptions) (string, error) {
+	if options.Table == "" {
+		return "", fmt.Errorf("missing table to copy into")
+	}
+	format, err := s3Format(options.S3Path, options.IAMRole, options.Format, copyFormats)
+	if err != nil {
+		return "", err
+	}
+	query := "COPY " + QuoteSchemaTable(options.Schema, options.Table)
+	if len(options.Columns) > 0 {
+		columns := make([]string, 0, len(options.Columns))
+		for _, col := range options.Columns {
+			columns = append(columns, QuoteIdentifier(col))
+		}
+		query += " (" + strings.Join(columns, ", ") + ")"
+	}
+	query += " FROM " + quoteLiteral(options.S3Path) + " IAM_ROLE " + quoteLiteral(options.IAMRole) + format
+	if options.Region != "" {
+		query += " REGION " + quoteLiteral(options.Region)
+	}
+	return query, nil
+}
+
+func unloadQuery(options UnloadOptions) (string, error) {
+	if strings.TrimSpace(options.Query) == "" {
+		return "", fmt.Errorf("missing query to unload")
+	}
+	format, err := s3Format(options.S3Path, options.IAMRole, options.Format, unloadFormats)
+	if err != nil {
+		return "", err
+	}
+	return "UNLOAD (" + quoteLiteral(options.Query) + ") TO " + quoteLiteral(options.S3Path) + " IAM_ROLE " + quoteLiteral(options.IAMRole) + format, nil
+}
+
+// s3Format validates the S3 options and returns the FORMAT clause, if any
+func s3Format(s3Path, iamRole, format string, formats map[string]string) (string, error) {
+	if !s3PathRegexp.MatchString(s3Path) {
+		return "", fmt.Errorf("invalid S3 path %q, expected s3://bucket/prefix", s3Path)
+	}
+	if !iamRoleARNRegexp.MatchString(iamRole) {
+		return "", fmt.Errorf("invalid IAM role ARN %q, expected arn:aws:iam::<account>:role/<name>", iamRole)
+	}
+	if format == "" {
+		return "", nil
+	}
+	clause, ok := formats[strings.ToUpper(format)]
+	if !ok {
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+	return " FORMAT AS " + clause, nil
+}
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/grafana/grafana-aws-sdk/pkg/sql/api"
+)
+
+var (
+	ResultError      = errors.New("error getting query result")
+	NoResultSetError = errors.New("statement did not produce a result set")
+	// CanceledError is returned when a call is interrupted because the request was canceled.
+	// It wraps context.Canceled.
+	CanceledError = fmt.Errorf("query canceled: %w", context.Canceled)
+	// TimeoutError is returned when a statement runs longer than the query timeout, it's canceled then
+	TimeoutError = errors.New("query timed out")
+	// CallTimeoutError is returned when a single AWS call runs longer than the callTimeout setting
+	CallTimeoutError = errors.New("call timed out")
+	// UnsupportedRegionError is returned when the SDK knows no endpoint for the region of the settings
+	UnsupportedRegionError = errors.New("unsupported region")
+	// UnsupportedAuthError is returned when the authentication of the settings isn't available for the
+	// cluster or workgroup, e.g. the temporary credentials of a database user with Redshift Serverless
+	UnsupportedAuthError = errors.New("unsupported authentication")
+	// NotExplainableError is returned by Explain for the statements without a plan, e.g. DDL statements
+	NotExplainableError = errors.New("statement can't be explained")
+	// InvalidSecretError is returned when a managed secret doesn't have the layout created by Redshift
+	InvalidSecretError = errors.New("invalid managed secret")
+	// PageLimitError is returned when a list has more pages than the configured maximum
+	PageLimitError = errors.New("too many pages")
+	// IncompleteListError is returned in strict mode when a list has entries without a name
+	IncompleteListError = errors.New("incomplete list")
+	// StatementNotFoundError is returned when the statement is still unknown after the not found retries.
+	// It's also an api.StatusError.
+	StatementNotFoundError = fmt.Errorf("%w: statement not found", api.StatusError)
+	// ExpiredResultTokenError is returned when a page of a result can no longer be fetched, the query must be run again.
+	// It's also a ResultError.
+	ExpiredResultTokenError = fmt.Errorf("%w: the result page token is invalid or expired, run the query again", ResultError)
+	// ResultTooLargeError is returned when a result exceeds the size the Data API can return, it's a limit
+	// of the service so the query must return less data or be exported with Unload. It's also a ResultError.
+	ResultTooLargeError = fmt.Errorf("%w: the result exceeds the maximum size returned by the Data API, select fewer rows or columns, or export it to S3 with UNLOAD", ResultError)
+	// ManagedSecretsDisabledError is returned when managed secrets are disabled by the settings
+	ManagedSecretsDisabledError = errors.New("managed secrets not enabled")
+	// The list errors are returned by the calls listing each kind of resource
+	ListDatabasesError  = errors.New("error listing databases")
+	ListSchemasError    = errors.New("error listing schemas")
+	ListTablesError     = errors.New("error listing tables")
+	DescribeTableError  = errors.New("error describing table")
+	ListSecretsError    = errors.New("error listing secrets")
+	ListStatementsError = errors.New("error listing statements")
+	// ClusterPausedError is also an api.ExecuteError so callers checking for the latter keep working
+	ClusterPausedError = fmt.Errorf("%w: the cluster is paused, resume it to run queries", api.ExecuteError)
+	// ClusterResumeError is returned when the paused cluster couldn't be resumed with the autoResume setting.
+	// It's also a ClusterPausedError.
+	ClusterResumeError = fmt.Errorf("%w: resuming the cluster failed", ClusterPausedError)
+)
+
+// StatementError is the error of a failed or aborted statement
+type StatementError struct {
+	// State is FAILED or ABORTED
+	State string
+	// Message is the error reported by Redshift
+	Message string
+	// ErrorID is the id Redshift appends to some messages (e.g. [ErrorId: 1-6411...]) to identify the failure, if any
+	ErrorID string
+}
+
+func (e *StatementError) Error() string {
+	return e.Message
+}
+
+var errorIDRegexp = regexp.MustCompile(`\[ErrorId: ([^\]]+)\]`)
+
+func newStatementError(state string, message *string) *StatementError {
+	res := &StatementError{State: state, Message: aws.StringValue(message)}
+	if res.Message == "" {
+		// The Data API may not report why a statement was aborted
+		res.Message = fmt.Sprintf("statement %s without an error message", strings.ToLower(state))
+	}
+	if m := errorIDRegexp.FindStringSubmatch(res.Message); m != nil {
+		res.ErrorID = m[1]
+	}
+	return res
+}
+
+// SyntaxError is the StatementError of a statement rejected by the SQL parser, with the location of the
+// error so the query editor can mark it. Line and Column are 1-based, computed from Position, the 1-based
+// character offset reported by Redshift, in the query. They're 0 if the query isn't known.
+type SyntaxError struct {
+	*StatementError
+	Line     int
+	Column   int
+	Position int
+	// Near is the token at which the parser stopped, empty at the end of the input
+	Near string
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.StatementError
+}
+
+// syntaxErrorRegexp matches messages like `ERROR: syntax error at or near "form" Position: 10`
+var syntaxErrorRegexp = regexp.MustCompile(`(?is)syntax error at (?:or near "((?:[^"]|"")*)"|end of input).*?position:\s*(\d+)`)
+
+// syntaxError returns a SyntaxError if the message of err has the position of a syntax error of the query,
+// otherwise err
+func syntaxError(err *StatementError, query string) error {
+	m := syntaxErrorRegexp.FindStringSubmatch(err.Message)
+	if m == nil {
+		return err
+	}
+	position, convErr := strconv.Atoi(m[2])
+	if convErr != nil || position < 1 {
+		return err
+	}
+	res := &SyntaxError{StatementError: err, Position: position, Near: strings.ReplaceAll(m[1], `""`, `"`)}
+	if runes := []rune(query); len(runes) > 0 && position <= len(runes)+1 {
+		res.Line, res.Column = 1, 1
+		for _, r := range runes[:position-1] {
+			if r == '\n' {
+				res.Line, res.Column = res.Line+1, 1
+			} else {
+				res.Column++
+			}
+		}
+	}
+	return res
+}
+
+// PermissionError is returned when a call is denied because the IAM role used by Grafana
+// lacks a permission. Action is the missing permission, if the AWS error names it.
+type PermissionError struct {
+	Action string
+	Err    error
+}
+
+func (e *PermissionError) Error() string {
+	if e.Action != "" {
+		return fmt.Sprintf("permission denied, grant %s to the IAM role used by Grafana: %v", e.Action, e.Err)
+	}
+	return fmt.Sprintf("permission denied, check the IAM policy of the role used by Grafana: %v", e.Err)
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// ClusterNotFoundError is returned when the cluster or workgroup of the settings doesn't exist in the region,
+// typically because of a typo in its identifier. Err is the error the call returns otherwise, e.g. an api.ExecuteError.
+type ClusterNotFoundError struct {
+	Identifier string
+	// Serverless is true if Identifier is the name of a workgroup
+	Serverless bool
+	// Region is empty when the region of the environment is used
+	Region string
+	Err    error
+}
+
+func (e *ClusterNotFoundError) Error() string {
+	kind := "cluster"
+	if e.Serverless {
+		kind = "workgroup"
+	}
+	region := e.Region
+	if region == "" {
+		region = "the default region"
+	}
+	return fmt.Sprintf("%s %q not found in %s, check the %s of the data source: %v", kind, e.Identifier, region, kind, e.Err)
+}
+
+func (e *ClusterNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// deniedActionRegexp matches the action of messages like
+// "User: arn:aws:sts::123:assumed-role/grafana is not authorized to perform: redshift-data:ExecuteStatement on resource: ..."
+var deniedActionRegexp = regexp.MustCompile(`not authorized to perform: ([\w-]+:\w+)`)
+
+// isPermissionError returns true if IAM denied the call. The message is checked as well
+// since the query errors only keep the text of the AWS error.
+func isPermissionError(err error) bool {
+	switch awsErrorCode(err) {
+	case "AccessDeniedException", "AccessDenied":
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "accessdenied") || deniedActionRegexp.MatchString(err.Error())
+}
+
+// permissionError returns a PermissionError wrapping wrapped if err is caused by a missing permission, otherwise wrapped
+func permissionError(err error, wrapped error) error {
+	if !isPermissionError(err) {
+		return wrapped
+	}
+	res := &PermissionError{Err: wrapped}
+	if m := deniedActionRegexp.FindStringSubmatch(err.Error()); m != nil {
+		res.Action = m[1]
+	}
+	return res
+}
+
+// secretValueError returns the error of GetSecretValue. The secrets encrypted with a customer managed key
+// are decrypted by Secrets Manager with the role used by Grafana, so a missing kms:Decrypt is named.
+func secretValueError(err error) error {
+	code := awsErrorCode(err)
+	if code == secretsmanager.ErrCodeDecryptionFailure || (isPermissionError(err) && strings.Contains(err.Error(), "KMS")) {
+		return &PermissionError{Action: "kms:Decrypt", Err: err}
+	}
+	return permissionError(err, err)
+}
+
+// awsErrorCode returns the code of an AWS error or an empty string for other errors
+func awsErrorCode(err error) string {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code()
+	}
+	return ""
+}
+
+// isAuthError returns true if the credentials of the call were rejected, either by IAM like isPermissionError
+// or by the database, e.g. the password of a managed secret that was rotated since it was read.
+func isAuthError(err error) bool {
+	return isPermissionError(err) || strings.Contains(strings.ToLower(err.Error()), "authentication failed")
+}
+
+// isStatementNotFoundError returns true if the statement ID is unknown, which happens
+// for a short time right after the statement is submitted
+func isStatementNotFoundError(err error) bool {
+	return awsErrorCode(err) == redshiftdataapiservice.ErrCodeResourceNotFoundException
+}
+
+// isExpiredTokenError returns true if the next token of a result page was rejected
+func isExpiredTokenError(err error) bool {
+	return awsErrorCode(err) == redshiftdataapiservice.ErrCodeValidationException && strings.Contains(strings.ToLower(err.Error()), "token")
+}
+
+// resultSizeLimitRegexp matches the messages of the results over the size limit, like
+// "Query result size exceeds the limit of 100 MB", but not the other sizes of the inputs, e.g. of a page
+var resultSizeLimitRegexp = regexp.MustCompile(`(?i)result\b.*\bexceeds?\b.*(\d+ ?mb|size limit|maximum (allowed )?size)`)
+
+// isResultTooLargeError returns true if the result of the statement is over the size limit of the Data API,
+// which rejects the call with a validation error
+func isResultTooLargeError(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != redshiftdataapiservice.ErrCodeValidationException {
+		return false
+	}
+	return resultSizeLimitRegexp.MatchString(awsErr.Message())
+}
+
+// isClusterStateError returns true if the Data API rejected the call because the cluster is in the given state,
+// e.g. "Cluster foo is paused". Other validation errors, like a missing column named paused, don't match.
+func isClusterStateError(err error, state string) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != redshiftdataapiservice.ErrCodeValidationException {
+		return false
+	}
+	msg := strings.ToLower(awsErr.Message())
+	return strings.HasPrefix(msg, "cluster ") && strings.Contains(msg, " is "+state)
+}
+
+// isClusterPausedError returns true if the statement was rejected because the cluster is paused
+func isClusterPausedError(err error) bool {
+	return isClusterStateError(err, "paused")
+}
+
+// isClusterResumingError returns true if the cluster is being resumed, in which case it will be available soon
+func isClusterResumingError(err error) bool {
+	return isClusterStateError(err, "resuming")
+}
+
+// isCanceled returns true if the call failed because its context was canceled
+func isCanceled(ctx context.Context, err error) bool {
+	if errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	return ctx.Err() == nil && awsErrorCode(err) == request.CanceledErrorCode
+}
+
+// clusterNotFoundRegexp matches the messages of the validation errors for an unknown cluster or workgroup,
+// like "Cluster foo not found." or "Redshift endpoint doesn't exist in this region."
+var clusterNotFoundRegexp = regexp.MustCompile(`(?i)^((redshift )?(serverless )?(cluster|workgroup)\b.*\bnot found|redshift endpoint does(n't| not) exist)`)
+
+// isClusterNotFoundError returns true if the Data API rejected the call because the cluster or workgroup
+// doesn't exist. It's a validation error, the unknown statement IDs are the ResourceNotFoundExceptions.
+func isClusterNotFoundError(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != redshiftdataapiservice.ErrCodeValidationException {
+		return false
+	}
+	return clusterNotFoundRegexp.MatchString(awsErr.Message())
+}
+
+// clusterNotFoundError returns a ClusterNotFoundError wrapping wrapped if err reports that the cluster
+// or workgroup of the settings doesn't exist, otherwise wrapped
+func (c *API) clusterNotFoundError(ctx context.Context, err error, wrapped error) error {
+	if !isClusterNotFoundError(err) {
+		return wrapped
+	}
+	input := c.apiInput(ctx)
+	res := &ClusterNotFoundError{Region: settingsRegion(c.settings), Err: wrapped}
+	if input.WorkgroupName != nil {
+		res.Identifier, res.Serverless = *input.WorkgroupName, true
+	} else {
+		res.Identifier = aws.StringValue(input.ClusterIdentifier)
+	}
+	return res
+}
+
+// listError wraps the error of a list call with the sentinel of the operation. Canceled calls
+// are CanceledErrors instead, timed out calls keep their CallTimeoutError, and missing permissions
+// are PermissionErrors wrapping the sentinel.
+func listError(ctx context.Context, sentinel error, err error) error {
+	if isCanceled(ctx, err) {
+		return fmt.Errorf("%w: %v", CanceledError, err)
+	}
+	if errors.Is(err, CallTimeoutError) {
+		return err
+	}
+	return permissionError(err, fmt.Errorf("%w: %v", sentinel, err))
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/grafana/grafana-aws-sdk/pkg/sql/api"
+)
+
+// explainableRegexp matches the statements Redshift can explain, CREATE TABLE AS included
+var explainableRegexp = regexp.MustCompile(`(?is)^\s*(select|with|insert|update|delete|create\s+(temp\s+|temporary\s+)?table\s+.+\s+as\b)`)
+
+// Explain returns the plan of the query without running it. Only SELECT, INSERT, UPDATE, DELETE and
+// CREATE TABLE AS statements can be explained. The EXPLAIN statement is run like any query, so it's
+// canceled with the context or once the query timeout is reached.
+func (c *API) Explain(ctx context.Context, query string) (string, error) {
+	return c.explain(ctx, query, false)
+}
+
+// ExplainVerbose is Explain with the full plan of EXPLAIN VERBOSE
+func (c *API) ExplainVerbose(ctx context.Context, query string) (string, error) {
+	return c.explain(ctx, query, true)
+}
+
+func (c *API) explain(ctx context.Context, query string, verbose bool) (string, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if strings.Contains(query, ";") {
+		return "", fmt.Errorf("%w: only a single statement can be explained", NotExplainableError)
+	}
+	if !explainableRegexp.MatchString(query) {
+		return "", fmt.Errorf("%w: only SELECT, INSERT, UPDATE, DELETE and CREATE TABLE AS statements can be explained", NotExplainableError)
+	}
+	explain := "EXPLAIN "
+	if verbose {
+		explain = "EXPLAIN VERBOSE "
+	}
+	res, err := c.ExecuteAndWait(ctx, &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: explain + query}})
+	if err != nil {
+		return "", err
+	}
+	// The plan is a QUERY PLAN column with a record per line
+	lines := make([]string, 0, len(res.Records))
+	for _, record := range res.Records {
+		if len(record) == 0 || record[0] == nil {
+			continue
+		}
+		lines = append(lines, aws.StringValue(record[0].StringValue))
+	}
+	return strings.Join(lines, "\n"), nil
+}
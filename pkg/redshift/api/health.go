@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/sql/api"
+	"github.com/grafana/sqlds/v2"
+)
+
+const defaultHealthCheckTimeout = 10 * time.Second
+
+// HealthCheckStage is the check of HealthCheck that failed
+type HealthCheckStage string
+
+const (
+	// HealthCheckSettingsInvalid is for settings missing the cluster, the database or the credentials
+	HealthCheckSettingsInvalid HealthCheckStage = "settings invalid"
+	// HealthCheckSecretUnreadable is for a managed secret that can't be read, e.g. because of a missing permission
+	HealthCheckSecretUnreadable HealthCheckStage = "secret unreadable"
+	// HealthCheckSecretMalformed is for a managed secret without the layout created by Redshift, or for another cluster
+	HealthCheckSecretMalformed HealthCheckStage = "secret malformed"
+	// HealthCheckQueryFailed is for a "SELECT 1" statement that couldn't run
+	HealthCheckQueryFailed HealthCheckStage = "query failed"
+)
+
+// HealthCheckError is returned by HealthCheck, Stage tells which check failed
+type HealthCheckError struct {
+	Stage HealthCheckStage
+	Err   error
+}
+
+func (e *HealthCheckError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HealthCheckError) Unwrap() error {
+	return e.Err
+}
+
+// HealthCheck validates the settings and runs a "SELECT 1" statement to verify that the cluster or
+// workgroup can be reached with the configured credentials. The statement is canceled if it doesn't finish in time.
+// With a managed secret, the secret is read and checked first so a misconfigured secret isn't
+// reported as a failed query.
+func (c *API) HealthCheck(ctx context.Context) error {
+	timeout := defaultHealthCheckTimeout
+	if c.settings != nil && c.settings.HealthCheckTimeout > 0 {
+		timeout = time.Duration(c.settings.HealthCheckTimeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The secret is checked first since the settings may be completed with its cluster and database
+	if err := c.checkSecret(ctx); err != nil {
+		return err
+	}
+	if err := c.CheckSettings(ctx); err != nil {
+		return &HealthCheckError{Stage: HealthCheckSettingsInvalid, Err: fmt.Errorf("health check failed, invalid settings: %w", err)}
+	}
+	output, err := c.ExecuteStatement(ctx, &StatementInput{ExecuteQueryInput: api.ExecuteQueryInput{Query: "SELECT 1"}})
+	if err != nil {
+		return healthCheckError(err)
+	}
+	if _, err := c.WaitOnQuery(ctx, output, 0); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			if stopErr := c.StopWithContext(context.Background(), output); stopErr != nil {
+				c.logger().Debug("failed to stop the health check statement", "query ID", output.ID, "error", stopErr.Error())
+			}
+			return &HealthCheckError{Stage: HealthCheckQueryFailed, Err: fmt.Errorf("health check failed: the statement did not finish within %s", timeout)}
+		}
+		return healthCheckError(err)
+	}
+	return nil
+}
+
+// checkSecret reads the managed secret of the settings, bypassing the cache, and checks that it's for the cluster
+func (c *API) checkSecret(ctx context.Context) error {
+	// A missing or disabled secret is reported by CheckSettings
+	if c.settings == nil || !c.settings.UseManagedSecret || c.settings.ManagedSecret.ARN == "" || c.settings.DisableManagedSecrets {
+		return nil
+	}
+	arn := c.settings.ManagedSecret.ARN
+	c.secrets.invalidate(arn)
+	secret, err := c.Secret(ctx, sqlds.Options{"secretARN": arn})
+	if err != nil {
+		if errors.Is(err, InvalidSecretError) {
+			return &HealthCheckError{Stage: HealthCheckSecretMalformed, Err: fmt.Errorf("health check failed, the managed secret is malformed: %w", err)}
+		}
+		return &HealthCheckError{Stage: HealthCheckSecretUnreadable, Err: fmt.Errorf("health check failed, unable to read the managed secret %s: %w", arn, err)}
+	}
+	if secret.ClusterIdentifier != "" && c.settings.ClusterIdentifier != "" && secret.ClusterIdentifier != c.settings.ClusterIdentifier {
+		return &HealthCheckError{Stage: HealthCheckSecretMalformed, Err: fmt.Errorf("health check failed, the managed secret %s is for the cluster %s, not %s", arn, secret.ClusterIdentifier, c.settings.ClusterIdentifier)}
+	}
+	return nil
+}
+
+func healthCheckError(err error) error {
+	switch {
+	case isAuthError(err):
+		err = fmt.Errorf("health check failed, check the credentials and the database user: %w", err)
+	case awsErrorCode(err) == "RequestError" || strings.Contains(err.Error(), "RequestError"):
+		err = fmt.Errorf("health check failed, unable to reach the Redshift Data API: %w", err)
+	default:
+		err = fmt.Errorf("health check failed: %w", err)
+	}
+	return &HealthCheckError{Stage: HealthCheckQueryFailed, Err: err}
+}
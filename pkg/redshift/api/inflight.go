@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// inflightStatements tracks the statements submitted with a context created with WithStatementCancellation,
+// so they are canceled on the cluster when the context is, instead of running until they finish. A statement
+// is removed once it's known to be done, when it's stopped or when its context is done.
+type inflightStatements struct {
+	mu         sync.Mutex
+	statements map[string]*inflightStatement
+}
+
+type inflightStatement struct {
+	done chan struct{}
+	// waited is true while WaitOnQuery polls the statement, which stops it itself when canceled
+	waited bool
+}
+
+// track cancels the statement when the context is canceled, unless it's removed before
+func (s *inflightStatements) track(ctx context.Context, id string, cancel func()) {
+	if ctx.Done() == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.statements == nil {
+		s.statements = map[string]*inflightStatement{}
+	}
+	if _, ok := s.statements[id]; ok {
+		s.mu.Unlock()
+		return
+	}
+	statement := &inflightStatement{done: make(chan struct{})}
+	s.statements[id] = statement
+	s.mu.Unlock()
+
+	go func() {
+		select {
+		case <-statement.done:
+		case <-ctx.Done():
+			if removed := s.remove(id); removed != nil && !removed.waited && errors.Is(ctx.Err(), context.Canceled) {
+				cancel()
+			}
+		}
+	}()
+}
+
+// setWaited records whether WaitOnQuery is polling the statement
+func (s *inflightStatements) setWaited(id string, waited bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if statement, ok := s.statements[id]; ok {
+		statement.waited = waited
+	}
+}
+
+// remove stops tracking the statement and returns it, nil if it wasn't tracked
+func (s *inflightStatements) remove(id string) *inflightStatement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statement, ok := s.statements[id]
+	if !ok {
+		return nil
+	}
+	delete(s.statements, id)
+	close(statement.done)
+	return statement
+}
+
+// len returns the number of statements tracked
+func (s *inflightStatements) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.statements)
+}
+
+// canceledStatementsTTL is how long the Data API keeps the statements, their status can't be described afterwards
+const canceledStatementsTTL = 24 * time.Hour
+
+// canceledStatements records the statements stopped by the API, so their ABORTED status is reported
+// as a cancellation rather than as a failure
+type canceledStatements struct {
+	mu         sync.Mutex
+	statements map[string]time.Time
+}
+
+// add records the statement, dropping the ones stopped before the statements TTL
+func (s *canceledStatements) add(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.statements == nil {
+		s.statements = map[string]time.Time{}
+	}
+	for canceled, at := range s.statements {
+		if now.Sub(at) > canceledStatementsTTL {
+			delete(s.statements, canceled)
+		}
+	}
+	s.statements[id] = now
+}
+
+// remove forgets the statement, e.g. because stopping it failed
+func (s *canceledStatements) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.statements, id)
+}
+
+// has returns true if the statement was stopped by the API
+func (s *canceledStatements) has(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.statements[id]
+	return ok
+}
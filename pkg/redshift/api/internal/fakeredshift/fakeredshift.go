@@ -0,0 +1,229 @@
+// Package fakeredshift is an in-memory Data API and Secrets Manager for the tests of the api package.
+// Unlike the mocks returning canned responses, it keeps the submitted statements and moves them
+// through their states, so the lifecycle of a statement can be tested from its submission to its result.
+package fakeredshift
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// Result is the result set of a statement
+type Result struct {
+	Columns []*redshiftdataapiservice.ColumnMetadata
+	Records [][]*redshiftdataapiservice.Field
+}
+
+// Statement is a statement submitted to the fake
+type Statement struct {
+	ID    string
+	Input *redshiftdataapiservice.ExecuteStatementInput
+	// States are returned in order by DescribeStatement, the last one is repeated
+	States []string
+	// Error is the error message of a FAILED statement
+	Error string
+	// Result is served by GetStatementResult once the statement is FINISHED, nil for a statement without result set
+	Result *Result
+	// Describes counts the calls to DescribeStatement, ResultPages the calls to GetStatementResult
+	Describes   int
+	ResultPages int
+	Canceled    bool
+}
+
+// state returns the current state of the statement, ABORTED once it's canceled
+func (s *Statement) state() string {
+	if s.Canceled {
+		return redshiftdataapiservice.StatusStringAborted
+	}
+	i := s.Describes - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(s.States) {
+		i = len(s.States) - 1
+	}
+	return s.States[i]
+}
+
+// Client implements the Data API and Secrets Manager interfaces, it's safe for concurrent use.
+// The methods that aren't implemented panic.
+type Client struct {
+	// States are the states of the new statements, [SUBMITTED, STARTED, FINISHED] if empty
+	States []string
+	// Error is the error message of the new statements, if their last state is FAILED
+	Error string
+	// Result is the result of the new statements, Results the results by SQL
+	Result  *Result
+	Results map[string]*Result
+	// PageSize is the number of records of each page of a result, 0 serves every record in a single page
+	PageSize int
+	// Secrets are the contents of the secrets by name, their ARN is arn:<name>
+	Secrets map[string]string
+
+	mu         sync.Mutex
+	statements map[string]*Statement
+	submitted  []string
+
+	redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
+	secretsmanageriface.SecretsManagerAPI
+}
+
+var defaultStates = []string{
+	redshiftdataapiservice.StatusStringSubmitted,
+	redshiftdataapiservice.StatusStringStarted,
+	redshiftdataapiservice.StatusStringFinished,
+}
+
+// Statement returns a copy of the submitted statement, nil if it's unknown
+func (c *Client) Statement(id string) *Statement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.statements[id]
+	if !ok {
+		return nil
+	}
+	res := *s
+	return &res
+}
+
+// Submitted returns the IDs of the submitted statements in order
+func (c *Client) Submitted() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.submitted...)
+}
+
+func notFound(id *string) error {
+	return awserr.New(redshiftdataapiservice.ErrCodeResourceNotFoundException, fmt.Sprintf("Query does not exist: %s", aws.StringValue(id)), nil)
+}
+
+func (c *Client) ExecuteStatementWithContext(_ aws.Context, input *redshiftdataapiservice.ExecuteStatementInput, _ ...request.Option) (*redshiftdataapiservice.ExecuteStatementOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.statements == nil {
+		c.statements = map[string]*Statement{}
+	}
+	s := &Statement{
+		ID:     fmt.Sprintf("statement-%d", len(c.submitted)+1),
+		Input:  input,
+		States: c.States,
+		Error:  c.Error,
+		Result: c.Result,
+	}
+	if len(s.States) == 0 {
+		s.States = defaultStates
+	}
+	if r, ok := c.Results[aws.StringValue(input.Sql)]; ok {
+		s.Result = r
+	}
+	c.statements[s.ID] = s
+	c.submitted = append(c.submitted, s.ID)
+	return &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String(s.ID)}, nil
+}
+
+func (c *Client) DescribeStatementWithContext(_ aws.Context, input *redshiftdataapiservice.DescribeStatementInput, _ ...request.Option) (*redshiftdataapiservice.DescribeStatementOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.statements[aws.StringValue(input.Id)]
+	if !ok {
+		return nil, notFound(input.Id)
+	}
+	s.Describes++
+	state := s.state()
+	res := &redshiftdataapiservice.DescribeStatementOutput{
+		Id:           aws.String(s.ID),
+		QueryString:  s.Input.Sql,
+		Status:       aws.String(state),
+		HasResultSet: aws.Bool(false),
+	}
+	switch state {
+	case redshiftdataapiservice.StatusStringFinished:
+		res.HasResultSet = aws.Bool(s.Result != nil)
+		if s.Result != nil {
+			res.ResultRows = aws.Int64(int64(len(s.Result.Records)))
+		}
+	case redshiftdataapiservice.StatusStringFailed:
+		res.Error = aws.String(s.Error)
+	}
+	return res, nil
+}
+
+func (c *Client) CancelStatementWithContext(_ aws.Context, input *redshiftdataapiservice.CancelStatementInput, _ ...request.Option) (*redshiftdataapiservice.CancelStatementOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.statements[aws.StringValue(input.Id)]
+	if !ok {
+		return nil, notFound(input.Id)
+	}
+	switch s.state() {
+	case redshiftdataapiservice.StatusStringFinished, redshiftdataapiservice.StatusStringFailed:
+		return nil, awserr.New(redshiftdataapiservice.ErrCodeValidationException, "Could not cancel a query that is already in FINISHED or FAILED state", nil)
+	}
+	s.Canceled = true
+	return &redshiftdataapiservice.CancelStatementOutput{Status: aws.Bool(true)}, nil
+}
+
+func (c *Client) GetStatementResultWithContext(_ aws.Context, input *redshiftdataapiservice.GetStatementResultInput, _ ...request.Option) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.statements[aws.StringValue(input.Id)]
+	if !ok {
+		return nil, notFound(input.Id)
+	}
+	if s.state() != redshiftdataapiservice.StatusStringFinished || s.Result == nil {
+		return nil, awserr.New(redshiftdataapiservice.ErrCodeResourceNotFoundException, "Query does not have result. Please check query status with DescribeStatement.", nil)
+	}
+	s.ResultPages++
+	records := s.Result.Records
+	start := 0
+	if input.NextToken != nil {
+		var err error
+		if start, err = strconv.Atoi(*input.NextToken); err != nil || start > len(records) {
+			return nil, awserr.New(redshiftdataapiservice.ErrCodeValidationException, "invalid next token", nil)
+		}
+	}
+	end := len(records)
+	if c.PageSize > 0 && start+c.PageSize < end {
+		end = start + c.PageSize
+	}
+	res := &redshiftdataapiservice.GetStatementResultOutput{
+		ColumnMetadata: s.Result.Columns,
+		Records:        records[start:end],
+		TotalNumRows:   aws.Int64(int64(len(records))),
+	}
+	if end < len(records) {
+		res.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return res, nil
+}
+
+func (c *Client) ListSecretsWithContext(_ aws.Context, _ *secretsmanager.ListSecretsInput, _ ...request.Option) (*secretsmanager.ListSecretsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := &secretsmanager.ListSecretsOutput{}
+	for name := range c.Secrets {
+		res.SecretList = append(res.SecretList, &secretsmanager.SecretListEntry{ARN: aws.String("arn:" + name), Name: aws.String(name)})
+	}
+	return res, nil
+}
+
+func (c *Client) GetSecretValueWithContext(_ aws.Context, input *secretsmanager.GetSecretValueInput, _ ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := aws.StringValue(input.SecretId)
+	for name, content := range c.Secrets {
+		if id == name || id == "arn:"+name {
+			return &secretsmanager.GetSecretValueOutput{ARN: aws.String("arn:" + name), Name: aws.String(name), SecretString: aws.String(content), VersionId: aws.String("v1")}, nil
+		}
+	}
+	return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret.", nil)
+}
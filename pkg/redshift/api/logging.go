@@ -0,0 +1,22 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func (c *API) logger() log.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return backend.Logger
+}
+
+// queryHash identifies a query in the logs without leaking its content
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,29 @@
+package api
+
+import "time"
+
+// Metrics receives measures about the Data API usage, e.g. to expose them as Prometheus metrics
+type Metrics interface {
+	// IncCall counts a call to a Data API operation (e.g. ExecuteStatement), retries included
+	IncCall(operation string)
+	// IncThrottle counts a call rejected because of the Data API limits
+	IncThrottle(operation string)
+	// ObserveDuration measures the latency of a call
+	ObserveDuration(operation string, duration time.Duration)
+	// ObserveQuery measures a finished statement
+	ObserveQuery(stats QueryStats)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) IncCall(string)                        {}
+func (noopMetrics) IncThrottle(string)                    {}
+func (noopMetrics) ObserveDuration(string, time.Duration) {}
+func (noopMetrics) ObserveQuery(QueryStats)               {}
+
+func (c *API) metrics() Metrics {
+	if c.Metrics != nil {
+		return c.Metrics
+	}
+	return noopMetrics{}
+}
@@ -2,30 +2,137 @@ package mock
 
 import (
 	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/redshift"
 	"github.com/aws/aws-sdk-go/service/redshift/redshiftiface"
 	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/redshiftserverless"
+	"github.com/aws/aws-sdk-go/service/redshiftserverless/redshiftserverlessiface"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 )
 
+// likePatternUnescaper reverts the escaping of the LIKE wildcards of the patterns
+var likePatternUnescaper = strings.NewReplacer(`\\`, `\`, `\%`, `%`, `\_`, `_`)
+
 type MockRedshiftClient struct {
-	ExecutionResult         *redshiftdataapiservice.ExecuteStatementOutput
-	DescribeStatementOutput *redshiftdataapiservice.DescribeStatementOutput
+	ExecutionResult *redshiftdataapiservice.ExecuteStatementOutput
+	// ExecuteStatementInput records the last input received by ExecuteStatement
+	ExecuteStatementInput *redshiftdataapiservice.ExecuteStatementInput
+	// ClientTokens records the client token of every call to ExecuteStatement
+	ClientTokens []string
+	// BatchExecuteStatementInput records the last input received by BatchExecuteStatement
+	BatchExecuteStatementInput *redshiftdataapiservice.BatchExecuteStatementInput
+	DescribeStatementOutput    *redshiftdataapiservice.DescribeStatementOutput
+	// Statuses are returned in order by DescribeStatement before DescribeStatementOutput
+	Statuses []string
+	// DescribeStatementErrors are returned in order by DescribeStatement before any status
+	DescribeStatementErrors []error
+	Databases               []string
+	// Results are the pages returned by GetStatementResult, NoResult makes it fail as for a DDL statement
+	Results  []*redshiftdataapiservice.GetStatementResultOutput
+	NoResult bool
+	// ResultPageErrors are returned instead of the page of the same index, ResultCalls counts the pages requested
+	ResultPageErrors map[int]error
+	// ResultPageFailures are returned in order for the page of the same index before it succeeds
+	ResultPageFailures map[int][]error
+	ResultCalls        int
+	// ThrottledCalls is the number of calls to ExecuteStatement and DescribeStatement failing with a throttling error
+	ThrottledCalls int
+	// Calls counts the calls to ExecuteStatement and DescribeStatement
+	Calls int
+	// CancelError makes CancelStatement fail, Canceled records that it was called
+	CancelError error
+	Canceled    bool
+	// Statements are returned by ListStatements, one per page. ListStatementsInput records the last input.
+	Statements          []*redshiftdataapiservice.StatementData
+	ListStatementsInput *redshiftdataapiservice.ListStatementsInput
+	// ListSchemasInput records the last input received by ListSchemas
+	ListSchemasInput *redshiftdataapiservice.ListSchemasInput
+	// ListTablesInput records the last input received by ListTables
+	ListTablesInput *redshiftdataapiservice.ListTablesInput
 	// Schemas > Tables > Columns
 	Resources map[string]map[string][]string
-	Secrets   []string
-	Secret    string
-	Clusters  []string
+	// TableTypes are the types of the tables by name, TABLE if not set
+	TableTypes map[string]string
+	// ColumnMetadata is returned by DescribeTable instead of the Resources columns
+	ColumnMetadata []*redshiftdataapiservice.ColumnMetadata
+	Secrets        []string
+	// UntaggedSecrets are only returned by ListSecrets without filters
+	UntaggedSecrets []string
+	// ListSecretsInput records the last input received by ListSecrets
+	ListSecretsInput *secretsmanager.ListSecretsInput
+	// SecretPages are returned by ListSecrets page by page instead of Secrets
+	SecretPages [][]string
+	Secret      string
+	// SecretBinary returns Secret as binary data instead of a string
+	SecretBinary bool
+	// SecretCalls counts the calls to GetSecretValue
+	SecretCalls int
+	// ListError makes ListDatabases, ListSchemas, ListTables, DescribeTable and ListStatements fail
+	ListError error
+	// SecretsError makes ListSecrets and GetSecretValue fail
+	SecretsError error
+	// SecretsThrottledCalls is the number of calls to ListSecrets and GetSecretValue failing with a throttling
+	// error, with the RetryAfter header if set
+	SecretsThrottledCalls int
+	RetryAfter            string
+	// ExecuteError makes ExecuteStatement fail, ExecuteErrors are returned in order before it
+	ExecuteError  error
+	ExecuteErrors []error
+	Clusters      []string
+	// ClusterStatuses are returned in order by DescribeClustersWithContext, the last one is repeated.
+	// ResumeError makes ResumeCluster fail, Resumed records that it was called.
+	ClusterStatuses []string
+	ResumeError     error
+	Resumed         bool
+	// ClusterDetails is returned by DescribeClustersWithContext when no status is set, DescribeClustersError makes it fail
+	ClusterDetails        *redshift.Cluster
+	DescribeClustersError error
+	// ClusterCredentialsInput records the last input received by GetClusterCredentials
+	ClusterCredentialsInput *redshift.GetClusterCredentialsInput
 
 	secretsmanageriface.SecretsManagerAPI
 	redshiftdataapiservice.RedshiftDataAPIService
 	redshiftiface.RedshiftAPI
 }
 
+type MockRedshiftServerlessClient struct {
+	// Workgroup is returned by GetWorkgroup, Error makes it fail
+	Workgroup *redshiftserverless.Workgroup
+	Error     error
+
+	redshiftserverlessiface.RedshiftServerlessAPI
+}
+
+func (m *MockRedshiftServerlessClient) GetWorkgroupWithContext(ctx aws.Context, input *redshiftserverless.GetWorkgroupInput, opts ...request.Option) (*redshiftserverless.GetWorkgroupOutput, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	if m.Workgroup == nil || aws.StringValue(m.Workgroup.WorkgroupName) != aws.StringValue(input.WorkgroupName) {
+		return nil, awserr.New(redshiftserverless.ErrCodeResourceNotFoundException, "workgroup not found", nil)
+	}
+	return &redshiftserverless.GetWorkgroupOutput{Workgroup: m.Workgroup}, nil
+}
+
+type MockEC2Client struct {
+	Regions []string
+	// Unauthorized makes DescribeRegions fail as if the role lacked ec2:DescribeRegions
+	Unauthorized bool
+
+	ec2iface.EC2API
+}
+
 type MockRedshiftClientError struct {
 	redshiftiface.RedshiftAPI
 }
@@ -34,51 +141,261 @@ type MockRedshiftClientNil struct {
 	redshiftiface.RedshiftAPI
 }
 
+func (m *MockRedshiftClient) throttle() error {
+	m.Calls++
+	if m.ThrottledCalls > 0 {
+		m.ThrottledCalls--
+		return awserr.New("ThrottlingException", "Rate exceeded", nil)
+	}
+	return nil
+}
+
+// throttleSecrets fails the Secrets Manager calls while SecretsThrottledCalls is positive. The
+// RetryAfter header is passed to the handlers of the request options.
+func (m *MockRedshiftClient) throttleSecrets(opts []request.Option) error {
+	if m.SecretsThrottledCalls <= 0 {
+		return nil
+	}
+	m.SecretsThrottledCalls--
+	r := &request.Request{HTTPResponse: &http.Response{Header: http.Header{}}}
+	if m.RetryAfter != "" {
+		r.HTTPResponse.Header.Set("Retry-After", m.RetryAfter)
+	}
+	r.ApplyOptions(opts...)
+	r.Handlers.Complete.Run(r)
+	return awserr.New("ThrottlingException", "Rate exceeded", nil)
+}
+
 func (m *MockRedshiftClient) ExecuteStatementWithContext(ctx aws.Context, input *redshiftdataapiservice.ExecuteStatementInput, opts ...request.Option) (*redshiftdataapiservice.ExecuteStatementOutput, error) {
+	m.ExecuteStatementInput = input
+	m.ClientTokens = append(m.ClientTokens, aws.StringValue(input.ClientToken))
+	if err := m.throttle(); err != nil {
+		return nil, err
+	}
+	if len(m.ExecuteErrors) > 0 {
+		err := m.ExecuteErrors[0]
+		m.ExecuteErrors = m.ExecuteErrors[1:]
+		return nil, err
+	}
+	if m.ExecuteError != nil {
+		return nil, m.ExecuteError
+	}
 	return m.ExecutionResult, nil
 }
 
+func (m *MockRedshiftClient) BatchExecuteStatementWithContext(ctx aws.Context, input *redshiftdataapiservice.BatchExecuteStatementInput, opts ...request.Option) (*redshiftdataapiservice.BatchExecuteStatementOutput, error) {
+	m.BatchExecuteStatementInput = input
+	if m.ExecuteError != nil {
+		return nil, m.ExecuteError
+	}
+	return &redshiftdataapiservice.BatchExecuteStatementOutput{Id: m.ExecutionResult.Id}, nil
+}
+
 func (m *MockRedshiftClient) DescribeStatementWithContext(_ aws.Context, input *redshiftdataapiservice.DescribeStatementInput, _ ...request.Option) (*redshiftdataapiservice.DescribeStatementOutput, error) {
+	if err := m.throttle(); err != nil {
+		return nil, err
+	}
+	if len(m.DescribeStatementErrors) > 0 {
+		err := m.DescribeStatementErrors[0]
+		m.DescribeStatementErrors = m.DescribeStatementErrors[1:]
+		return nil, err
+	}
+	if len(m.Statuses) > 0 {
+		status := m.Statuses[0]
+		m.Statuses = m.Statuses[1:]
+		return &redshiftdataapiservice.DescribeStatementOutput{Id: input.Id, Status: aws.String(status)}, nil
+	}
 	return m.DescribeStatementOutput, nil
 }
 
+func (m *MockRedshiftClient) CancelStatementWithContext(ctx aws.Context, input *redshiftdataapiservice.CancelStatementInput, opts ...request.Option) (*redshiftdataapiservice.CancelStatementOutput, error) {
+	m.Canceled = true
+	if m.CancelError != nil {
+		return nil, m.CancelError
+	}
+	return &redshiftdataapiservice.CancelStatementOutput{Status: aws.Bool(true)}, nil
+}
+
+func (m *MockRedshiftClient) GetStatementResultWithContext(ctx aws.Context, input *redshiftdataapiservice.GetStatementResultInput, opts ...request.Option) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	if m.NoResult {
+		return nil, awserr.New(redshiftdataapiservice.ErrCodeResourceNotFoundException, "Query does not have result.", nil)
+	}
+	m.ResultCalls++
+	page := 0
+	if input.NextToken != nil {
+		page, _ = strconv.Atoi(*input.NextToken)
+	}
+	if errs := m.ResultPageFailures[page]; len(errs) > 0 {
+		m.ResultPageFailures[page] = errs[1:]
+		return nil, errs[0]
+	}
+	if err := m.ResultPageErrors[page]; err != nil {
+		return nil, err
+	}
+	res := *m.Results[page]
+	if page+1 < len(m.Results) {
+		res.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return &res, nil
+}
+
+func (m *MockRedshiftClient) ListStatementsWithContext(ctx aws.Context, input *redshiftdataapiservice.ListStatementsInput, opts ...request.Option) (*redshiftdataapiservice.ListStatementsOutput, error) {
+	if m.ListError != nil {
+		return nil, m.ListError
+	}
+	m.ListStatementsInput = input
+	page := 0
+	if input.NextToken != nil {
+		page, _ = strconv.Atoi(*input.NextToken)
+	}
+	out := &redshiftdataapiservice.ListStatementsOutput{}
+	if page < len(m.Statements) {
+		out.Statements = []*redshiftdataapiservice.StatementData{m.Statements[page]}
+	}
+	if page+1 < len(m.Statements) {
+		out.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return out, nil
+}
+
+func (m *MockRedshiftClient) ListDatabasesWithContext(ctx aws.Context, input *redshiftdataapiservice.ListDatabasesInput, opts ...request.Option) (*redshiftdataapiservice.ListDatabasesOutput, error) {
+	if m.ListError != nil {
+		return nil, m.ListError
+	}
+	res := &redshiftdataapiservice.ListDatabasesOutput{}
+	for _, db := range m.Databases {
+		res.Databases = append(res.Databases, aws.String(db))
+	}
+	return res, nil
+}
+
+// page returns the items of the page requested with a token and a max number of results.
+// Without max results every item is returned, otherwise they are sorted and the token is an offset.
+func page(items []string, token *string, maxResults *int64) ([]string, *string) {
+	if maxResults == nil {
+		return items, nil
+	}
+	sort.Strings(items)
+	start := 0
+	if token != nil {
+		start, _ = strconv.Atoi(*token)
+	}
+	end := start + int(*maxResults)
+	if end >= len(items) {
+		return items[start:], nil
+	}
+	return items[start:end], aws.String(strconv.Itoa(end))
+}
+
 func (m *MockRedshiftClient) ListSchemasWithContext(ctx aws.Context, input *redshiftdataapiservice.ListSchemasInput, opts ...request.Option) (*redshiftdataapiservice.ListSchemasOutput, error) {
-	res := &redshiftdataapiservice.ListSchemasOutput{}
+	if m.ListError != nil {
+		return nil, m.ListError
+	}
+	m.ListSchemasInput = input
+	schemas := []string{}
 	for sc := range m.Resources {
-		res.Schemas = append(res.Schemas, aws.String(sc))
+		// Only prefix patterns (e.g. foo%) are supported by the mock, their escaped wildcards are matched literally
+		if input.SchemaPattern != nil {
+			prefix := strings.TrimSuffix(*input.SchemaPattern, "%")
+			if !strings.HasPrefix(sc, likePatternUnescaper.Replace(prefix)) {
+				continue
+			}
+		}
+		schemas = append(schemas, sc)
 	}
-	return res, nil
+	schemas, next := page(schemas, input.NextToken, input.MaxResults)
+	return &redshiftdataapiservice.ListSchemasOutput{Schemas: aws.StringSlice(schemas), NextToken: next}, nil
 }
 
 func (m *MockRedshiftClient) ListTablesWithContext(ctx aws.Context, input *redshiftdataapiservice.ListTablesInput, opts ...request.Option) (*redshiftdataapiservice.ListTablesOutput, error) {
-	res := &redshiftdataapiservice.ListTablesOutput{}
-	for t := range m.Resources[*input.SchemaPattern] {
-		res.Tables = append(res.Tables, &redshiftdataapiservice.TableMember{Name: aws.String(t)})
+	if m.ListError != nil {
+		return nil, m.ListError
+	}
+	m.ListTablesInput = input
+	// The schema pattern is matched literally, only escaped wildcards are supported by the mock
+	schema := likePatternUnescaper.Replace(*input.SchemaPattern)
+	tables := []string{}
+	for t := range m.Resources[schema] {
+		// Only prefix patterns (e.g. foo%) are supported by the mock
+		if input.TablePattern != nil && !strings.HasPrefix(t, strings.TrimSuffix(*input.TablePattern, "%")) {
+			continue
+		}
+		tables = append(tables, t)
+	}
+	tables, next := page(tables, input.NextToken, input.MaxResults)
+	res := &redshiftdataapiservice.ListTablesOutput{NextToken: next}
+	for _, t := range tables {
+		tableType, ok := m.TableTypes[t]
+		if !ok {
+			tableType = "TABLE"
+		}
+		res.Tables = append(res.Tables, &redshiftdataapiservice.TableMember{Name: aws.String(t), Schema: aws.String(schema), Type: aws.String(tableType)})
 	}
 	return res, nil
 }
 
 func (m *MockRedshiftClient) DescribeTableWithContext(ctx aws.Context, input *redshiftdataapiservice.DescribeTableInput, opts ...request.Option) (*redshiftdataapiservice.DescribeTableOutput, error) {
+	if m.ListError != nil {
+		return nil, m.ListError
+	}
 	res := &redshiftdataapiservice.DescribeTableOutput{}
+	if m.ColumnMetadata != nil {
+		res.ColumnList = m.ColumnMetadata
+		return res, nil
+	}
 	tables := m.Resources[*input.Schema]
-	for _, c := range tables[*input.Table] {
+	columns, next := page(append([]string{}, tables[*input.Table]...), input.NextToken, input.MaxResults)
+	res.NextToken = next
+	for _, c := range columns {
 		res.ColumnList = append(res.ColumnList, &redshiftdataapiservice.ColumnMetadata{Name: aws.String(c)})
 	}
 	return res, nil
 }
 
 func (m *MockRedshiftClient) ListSecretsWithContext(ctx aws.Context, input *secretsmanager.ListSecretsInput, opts ...request.Option) (*secretsmanager.ListSecretsOutput, error) {
+	m.ListSecretsInput = input
+	if m.SecretsError != nil {
+		return nil, m.SecretsError
+	}
+	if err := m.throttleSecrets(opts); err != nil {
+		return nil, err
+	}
 	r := &secretsmanager.ListSecretsOutput{}
-	for _, c := range m.Secrets {
+	secrets := m.Secrets
+	if len(input.Filters) == 0 {
+		secrets = append(append([]string{}, m.Secrets...), m.UntaggedSecrets...)
+	}
+	if len(m.SecretPages) > 0 {
+		page := 0
+		if input.NextToken != nil {
+			page, _ = strconv.Atoi(*input.NextToken)
+		}
+		secrets = m.SecretPages[page]
+		if page+1 < len(m.SecretPages) {
+			r.NextToken = aws.String(strconv.Itoa(page + 1))
+		}
+	}
+	for _, c := range secrets {
 		r.SecretList = append(r.SecretList, &secretsmanager.SecretListEntry{ARN: aws.String(fmt.Sprintf("arn:%s", c)), Name: aws.String(c)})
 	}
 	return r, nil
 }
 
 func (m *MockRedshiftClient) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
-	return &secretsmanager.GetSecretValueOutput{
-		SecretString: aws.String(m.Secret),
-	}, nil
+	m.SecretCalls++
+	if m.SecretsError != nil {
+		return nil, m.SecretsError
+	}
+	if err := m.throttleSecrets(opts); err != nil {
+		return nil, err
+	}
+	out := &secretsmanager.GetSecretValueOutput{VersionId: aws.String(fmt.Sprintf("v%d", m.SecretCalls))}
+	if m.SecretBinary {
+		out.SecretBinary = []byte(m.Secret)
+	} else {
+		out.SecretString = aws.String(m.Secret)
+	}
+	return out, nil
 }
 
 func (m *MockRedshiftClient) DescribeClusters(input *redshift.DescribeClustersInput) (*redshift.DescribeClustersOutput, error) {
@@ -86,9 +403,9 @@ func (m *MockRedshiftClient) DescribeClusters(input *redshift.DescribeClustersIn
 	for _, c := range m.Clusters {
 		r = append(r, &redshift.Cluster{
 			ClusterIdentifier: aws.String(c),
-			Endpoint: &redshift.Endpoint {
+			Endpoint: &redshift.Endpoint{
 				Address: aws.String(c),
-				Port: aws.Int64(123),
+				Port:    aws.Int64(123),
 			},
 			DBName: aws.String(c),
 		})
@@ -99,9 +416,68 @@ func (m *MockRedshiftClient) DescribeClusters(input *redshift.DescribeClustersIn
 	return &res, nil
 }
 
+func (m *MockRedshiftClient) ResumeClusterWithContext(ctx aws.Context, input *redshift.ResumeClusterInput, opts ...request.Option) (*redshift.ResumeClusterOutput, error) {
+	m.Resumed = true
+	if m.ResumeError != nil {
+		return nil, m.ResumeError
+	}
+	return &redshift.ResumeClusterOutput{}, nil
+}
+
+func (m *MockRedshiftClient) DescribeClustersWithContext(ctx aws.Context, input *redshift.DescribeClustersInput, opts ...request.Option) (*redshift.DescribeClustersOutput, error) {
+	if m.DescribeClustersError != nil {
+		return nil, m.DescribeClustersError
+	}
+	if len(m.ClusterStatuses) == 0 {
+		res := &redshift.DescribeClustersOutput{}
+		if m.ClusterDetails != nil {
+			res.Clusters = []*redshift.Cluster{m.ClusterDetails}
+		}
+		return res, nil
+	}
+	status := m.ClusterStatuses[0]
+	if len(m.ClusterStatuses) > 1 {
+		m.ClusterStatuses = m.ClusterStatuses[1:]
+	}
+	return &redshift.DescribeClustersOutput{Clusters: []*redshift.Cluster{{ClusterIdentifier: input.ClusterIdentifier, ClusterStatus: aws.String(status)}}}, nil
+}
+
+func (m *MockRedshiftClient) GetClusterCredentialsWithContext(ctx aws.Context, input *redshift.GetClusterCredentialsInput, opts ...request.Option) (*redshift.GetClusterCredentialsOutput, error) {
+	m.ClusterCredentialsInput = input
+	return &redshift.GetClusterCredentialsOutput{DbUser: aws.String("IAM:" + *input.DbUser), DbPassword: aws.String("password")}, nil
+}
+
 func (m *MockRedshiftClientError) DescribeClusters(input *redshift.DescribeClustersInput) (*redshift.DescribeClustersOutput, error) {
 	return nil, fmt.Errorf("Boom!")
 }
 func (m *MockRedshiftClientNil) DescribeClusters(input *redshift.DescribeClustersInput) (*redshift.DescribeClustersOutput, error) {
 	return nil, nil
 }
+
+func (m *MockEC2Client) DescribeRegionsWithContext(ctx aws.Context, input *ec2.DescribeRegionsInput, opts ...request.Option) (*ec2.DescribeRegionsOutput, error) {
+	if m.Unauthorized {
+		return nil, awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+	}
+	res := &ec2.DescribeRegionsOutput{}
+	for _, r := range m.Regions {
+		res.Regions = append(res.Regions, &ec2.Region{RegionName: aws.String(r)})
+	}
+	return res, nil
+}
+
+// MockLogger records the messages logged and their arguments
+type MockLogger struct {
+	Messages []string
+	Args     []interface{}
+}
+
+func (l *MockLogger) record(msg string, args ...interface{}) {
+	l.Messages = append(l.Messages, msg)
+	l.Args = append(l.Args, args...)
+}
+
+func (l *MockLogger) Debug(msg string, args ...interface{}) { l.record(msg, args...) }
+func (l *MockLogger) Info(msg string, args ...interface{})  { l.record(msg, args...) }
+func (l *MockLogger) Warn(msg string, args ...interface{})  { l.record(msg, args...) }
+func (l *MockLogger) Error(msg string, args ...interface{}) { l.record(msg, args...) }
+func (l *MockLogger) Level() log.Level                      { return log.Debug }
@@ -0,0 +1,246 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	sqlAPI "github.com/grafana/grafana-aws-sdk/pkg/sql/api"
+	"github.com/grafana/redshift-datasource/pkg/redshift/api"
+	"github.com/grafana/redshift-datasource/pkg/redshift/models"
+	"github.com/grafana/sqlds/v2"
+)
+
+// RedshiftAPI is a mock of api.RedshiftAPI. Each method calls the function of the same name with
+// the Func suffix if it's set, otherwise it returns zero values. Calls records the names of the methods called.
+//
+//	m := &mocks.RedshiftAPI{
+//		TablesFunc: func(ctx aws.Context, options sqlds.Options) ([]string, error) {
+//			return []string{"foo"}, nil
+//		},
+//	}
+type RedshiftAPI struct {
+	ExecuteFunc          func(ctx aws.Context, input *sqlAPI.ExecuteQueryInput) (*sqlAPI.ExecuteQueryOutput, error)
+	StatusFunc           func(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput) (*sqlAPI.ExecuteQueryStatus, error)
+	StopFunc             func(output *sqlAPI.ExecuteQueryOutput) error
+	RegionsFunc          func(ctx aws.Context) ([]string, error)
+	DatabasesFunc        func(ctx aws.Context, options sqlds.Options) ([]string, error)
+	ExecuteStatementFunc func(ctx context.Context, input *api.StatementInput) (*sqlAPI.ExecuteQueryOutput, error)
+	BatchExecuteFunc     func(ctx context.Context, queries []string) (*sqlAPI.ExecuteQueryOutput, error)
+	ExecuteAndWaitFunc   func(ctx context.Context, input *api.StatementInput) (*api.Result, error)
+	StatementStatusFunc  func(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput) (*api.StatementStatus, error)
+	WaitOnQueryFunc      func(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput, pollInterval time.Duration) (*api.StatementStatus, error)
+	StopWithContextFunc  func(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput) error
+	GetResultFunc        func(ctx aws.Context, id string, maxRows int) (*redshiftdataapiservice.GetStatementResultOutput, error)
+	ListStatementsFunc   func(ctx aws.Context, filter api.ListStatementsFilter) ([]api.StatementSummary, error)
+	SchemasFunc          func(ctx aws.Context, options sqlds.Options) ([]string, error)
+	TablesFunc           func(ctx aws.Context, options sqlds.Options) ([]string, error)
+	ColumnsFunc          func(ctx aws.Context, options sqlds.Options) ([]string, error)
+	ColumnsWithTypesFunc func(ctx aws.Context, options sqlds.Options) ([]api.Column, error)
+	SecretsFunc          func(ctx aws.Context) ([]models.ManagedSecret, error)
+	SecretFunc           func(ctx aws.Context, options sqlds.Options) (*models.RedshiftSecret, error)
+	ClustersFunc         func() ([]models.RedshiftCluster, error)
+	HealthCheckFunc      func(ctx context.Context) error
+	SettingsFunc         func() *models.RedshiftDataSourceSettings
+	CloseFunc            func() error
+
+	Calls []string
+}
+
+var _ api.RedshiftAPI = (*RedshiftAPI)(nil)
+
+func (m *RedshiftAPI) call(method string) {
+	m.Calls = append(m.Calls, method)
+}
+
+func (m *RedshiftAPI) Execute(ctx aws.Context, input *sqlAPI.ExecuteQueryInput) (*sqlAPI.ExecuteQueryOutput, error) {
+	m.call("Execute")
+	if m.ExecuteFunc == nil {
+		return &sqlAPI.ExecuteQueryOutput{}, nil
+	}
+	return m.ExecuteFunc(ctx, input)
+}
+
+func (m *RedshiftAPI) Status(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput) (*sqlAPI.ExecuteQueryStatus, error) {
+	m.call("Status")
+	if m.StatusFunc == nil {
+		return &sqlAPI.ExecuteQueryStatus{ID: output.ID, Finished: true, State: redshiftdataapiservice.StatusStringFinished}, nil
+	}
+	return m.StatusFunc(ctx, output)
+}
+
+func (m *RedshiftAPI) Stop(output *sqlAPI.ExecuteQueryOutput) error {
+	m.call("Stop")
+	if m.StopFunc == nil {
+		return nil
+	}
+	return m.StopFunc(output)
+}
+
+func (m *RedshiftAPI) Regions(ctx aws.Context) ([]string, error) {
+	m.call("Regions")
+	if m.RegionsFunc == nil {
+		return []string{}, nil
+	}
+	return m.RegionsFunc(ctx)
+}
+
+func (m *RedshiftAPI) Databases(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	m.call("Databases")
+	if m.DatabasesFunc == nil {
+		return []string{}, nil
+	}
+	return m.DatabasesFunc(ctx, options)
+}
+
+func (m *RedshiftAPI) ExecuteStatement(ctx context.Context, input *api.StatementInput) (*sqlAPI.ExecuteQueryOutput, error) {
+	m.call("ExecuteStatement")
+	if m.ExecuteStatementFunc == nil {
+		return &sqlAPI.ExecuteQueryOutput{}, nil
+	}
+	return m.ExecuteStatementFunc(ctx, input)
+}
+
+func (m *RedshiftAPI) BatchExecute(ctx context.Context, queries []string) (*sqlAPI.ExecuteQueryOutput, error) {
+	m.call("BatchExecute")
+	if m.BatchExecuteFunc == nil {
+		return &sqlAPI.ExecuteQueryOutput{}, nil
+	}
+	return m.BatchExecuteFunc(ctx, queries)
+}
+
+func (m *RedshiftAPI) ExecuteAndWait(ctx context.Context, input *api.StatementInput) (*api.Result, error) {
+	m.call("ExecuteAndWait")
+	if m.ExecuteAndWaitFunc == nil {
+		return &api.Result{}, nil
+	}
+	return m.ExecuteAndWaitFunc(ctx, input)
+}
+
+func (m *RedshiftAPI) StatementStatus(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput) (*api.StatementStatus, error) {
+	m.call("StatementStatus")
+	if m.StatementStatusFunc == nil {
+		return finished(output), nil
+	}
+	return m.StatementStatusFunc(ctx, output)
+}
+
+func (m *RedshiftAPI) WaitOnQuery(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput, pollInterval time.Duration) (*api.StatementStatus, error) {
+	m.call("WaitOnQuery")
+	if m.WaitOnQueryFunc == nil {
+		return finished(output), nil
+	}
+	return m.WaitOnQueryFunc(ctx, output, pollInterval)
+}
+
+func (m *RedshiftAPI) StopWithContext(ctx aws.Context, output *sqlAPI.ExecuteQueryOutput) error {
+	m.call("StopWithContext")
+	if m.StopWithContextFunc == nil {
+		return nil
+	}
+	return m.StopWithContextFunc(ctx, output)
+}
+
+func (m *RedshiftAPI) GetResult(ctx aws.Context, id string, maxRows int) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	m.call("GetResult")
+	if m.GetResultFunc == nil {
+		return &redshiftdataapiservice.GetStatementResultOutput{}, nil
+	}
+	return m.GetResultFunc(ctx, id, maxRows)
+}
+
+func (m *RedshiftAPI) ListStatements(ctx aws.Context, filter api.ListStatementsFilter) ([]api.StatementSummary, error) {
+	m.call("ListStatements")
+	if m.ListStatementsFunc == nil {
+		return []api.StatementSummary{}, nil
+	}
+	return m.ListStatementsFunc(ctx, filter)
+}
+
+func (m *RedshiftAPI) Schemas(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	m.call("Schemas")
+	if m.SchemasFunc == nil {
+		return []string{}, nil
+	}
+	return m.SchemasFunc(ctx, options)
+}
+
+func (m *RedshiftAPI) Tables(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	m.call("Tables")
+	if m.TablesFunc == nil {
+		return []string{}, nil
+	}
+	return m.TablesFunc(ctx, options)
+}
+
+func (m *RedshiftAPI) Columns(ctx aws.Context, options sqlds.Options) ([]string, error) {
+	m.call("Columns")
+	if m.ColumnsFunc == nil {
+		return []string{}, nil
+	}
+	return m.ColumnsFunc(ctx, options)
+}
+
+func (m *RedshiftAPI) ColumnsWithTypes(ctx aws.Context, options sqlds.Options) ([]api.Column, error) {
+	m.call("ColumnsWithTypes")
+	if m.ColumnsWithTypesFunc == nil {
+		return []api.Column{}, nil
+	}
+	return m.ColumnsWithTypesFunc(ctx, options)
+}
+
+func (m *RedshiftAPI) Secrets(ctx aws.Context) ([]models.ManagedSecret, error) {
+	m.call("Secrets")
+	if m.SecretsFunc == nil {
+		return []models.ManagedSecret{}, nil
+	}
+	return m.SecretsFunc(ctx)
+}
+
+func (m *RedshiftAPI) Secret(ctx aws.Context, options sqlds.Options) (*models.RedshiftSecret, error) {
+	m.call("Secret")
+	if m.SecretFunc == nil {
+		return &models.RedshiftSecret{}, nil
+	}
+	return m.SecretFunc(ctx, options)
+}
+
+func (m *RedshiftAPI) Clusters() ([]models.RedshiftCluster, error) {
+	m.call("Clusters")
+	if m.ClustersFunc == nil {
+		return []models.RedshiftCluster{}, nil
+	}
+	return m.ClustersFunc()
+}
+
+func (m *RedshiftAPI) HealthCheck(ctx context.Context) error {
+	m.call("HealthCheck")
+	if m.HealthCheckFunc == nil {
+		return nil
+	}
+	return m.HealthCheckFunc(ctx)
+}
+
+func (m *RedshiftAPI) Settings() *models.RedshiftDataSourceSettings {
+	m.call("Settings")
+	if m.SettingsFunc == nil {
+		return &models.RedshiftDataSourceSettings{}
+	}
+	return m.SettingsFunc()
+}
+
+func (m *RedshiftAPI) Close() error {
+	m.call("Close")
+	if m.CloseFunc == nil {
+		return nil
+	}
+	return m.CloseFunc()
+}
+
+func finished(output *sqlAPI.ExecuteQueryOutput) *api.StatementStatus {
+	return &api.StatementStatus{
+		ExecuteQueryStatus: sqlAPI.ExecuteQueryStatus{ID: output.ID, Finished: true, State: redshiftdataapiservice.StatusStringFinished},
+		Phase:              api.PhaseFinished,
+	}
+}
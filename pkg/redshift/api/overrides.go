@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/redshift-datasource/pkg/redshift/models"
+)
+
+// Overrides replace the cluster, database or secret of the settings for the calls made with a context,
+// e.g. to serve several tenants with a single data source. The empty fields keep the settings.
+type Overrides struct {
+	// ClusterIdentifier and WorkgroupName are mutually exclusive, either replaces both settings
+	ClusterIdentifier string
+	WorkgroupName     string
+	Database          string
+	// SecretARN replaces the managed secret or the database user of the settings
+	SecretARN string
+}
+
+type overridesKey struct{}
+
+// WithOverrides returns a context with which the statements, lists and caches of the API use the overrides.
+// The overrides are validated against the settings, which aren't modified: they don't persist after the
+// calls made with the context, and other calls made concurrently keep using the settings.
+func (c *API) WithOverrides(ctx context.Context, overrides Overrides) (context.Context, error) {
+	if overrides.ClusterIdentifier != "" && overrides.WorkgroupName != "" {
+		return nil, fmt.Errorf("cluster identifier and workgroup name overrides are mutually exclusive, only one of them can be set")
+	}
+	settings, err := c.settingsFromSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSettings(overrides.apply(settings)); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, overridesKey{}, overrides), nil
+}
+
+// overriddenSettings returns a copy of the settings with the overrides of the context, or the settings if there are none.
+// The settings are completed with the managed secret, see settingsFromSecret.
+func (c *API) overriddenSettings(ctx context.Context) *models.RedshiftDataSourceSettings {
+	settings, err := c.settingsFromSecret(ctx)
+	if err != nil {
+		c.logger().Debug("using the settings without the managed secret", "error", err.Error())
+	}
+	overrides, ok := ctx.Value(overridesKey{}).(Overrides)
+	if !ok {
+		return settings
+	}
+	return overrides.apply(settings)
+}
+
+func (o Overrides) apply(settings *models.RedshiftDataSourceSettings) *models.RedshiftDataSourceSettings {
+	res := *settings
+	if o.ClusterIdentifier != "" {
+		res.ClusterIdentifier, res.WorkgroupName = o.ClusterIdentifier, ""
+	}
+	if o.WorkgroupName != "" {
+		res.ClusterIdentifier, res.WorkgroupName = "", o.WorkgroupName
+	}
+	if o.Database != "" {
+		res.Database = o.Database
+	}
+	if o.SecretARN != "" {
+		res.QuerySecretARN = o.SecretARN
+	}
+	return &res
+}
@@ -0,0 +1,23 @@
+package api
+
+import "strings"
+
+// QuoteIdentifier quotes a schema, table or column name so it's used as is in a statement, whatever
+// its case or characters. Embedded double quotes are doubled. The name must not be quoted already.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteSchemaTable quotes a table name qualified by its schema, the table is looked up in the
+// search path if the schema is empty
+func QuoteSchemaTable(schema, table string) string {
+	if schema == "" {
+		return QuoteIdentifier(table)
+	}
+	return QuoteIdentifier(schema) + "." + QuoteIdentifier(table)
+}
+
+// quoteLiteral quotes a string literal. Backslashes are escaped too since Redshift treats them as escapes.
+func quoteLiteral(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(value) + "'"
+}
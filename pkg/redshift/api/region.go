@@ -0,0 +1,74 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/redshift"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	awsModels "github.com/grafana/grafana-aws-sdk/pkg/sql/models"
+	"github.com/grafana/redshift-datasource/pkg/redshift/models"
+)
+
+// validateRegions normalizes the regions of the settings and checks that the SDK knows an endpoint for them,
+// so a typo fails with the valid regions instead of a DNS error on the first call. It's skipped with allowUnknownRegion.
+func validateRegions(settings *models.RedshiftDataSourceSettings) error {
+	settings.Region = normalizeRegion(settings.Region)
+	settings.DefaultRegion = normalizeRegion(settings.DefaultRegion)
+	settings.SecretsRegion = normalizeRegion(settings.SecretsRegion)
+	if settings.AllowUnknownRegion {
+		return nil
+	}
+	region := settingsRegion(settings)
+	// Without a region, the one of the environment is used by the SDK. The endpoint table of the
+	// SDK has no entry for the Data API, which is available in the regions of Redshift.
+	if err := validateRegion(region, redshift.EndpointsID); err != nil {
+		return err
+	}
+	return validateRegion(settings.SecretsRegion, secretsmanager.EndpointsID)
+}
+
+// settingsRegion returns the region of the Redshift calls, empty if it's the one of the environment
+func settingsRegion(settings *models.RedshiftDataSourceSettings) string {
+	if settings.Region == "" || settings.Region == awsModels.DefaultKey {
+		return settings.DefaultRegion
+	}
+	return settings.Region
+}
+
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.TrimSpace(region))
+}
+
+func validateRegion(region string, service string) error {
+	if region == "" {
+		return nil
+	}
+	regions := serviceRegions(service)
+	i := sort.SearchStrings(regions, region)
+	if i < len(regions) && regions[i] == region {
+		return nil
+	}
+	return fmt.Errorf("%w %q for %s, valid regions are: %s", UnsupportedRegionError, region, service, strings.Join(regions, ", "))
+}
+
+// serviceRegions returns the sorted regions of every partition in which the SDK knows an endpoint of the service
+func serviceRegions(service string) []string {
+	regions := []string{}
+	for _, partition := range endpoints.DefaultPartitions() {
+		s, ok := partition.Services()[service]
+		if !ok {
+			continue
+		}
+		known := partition.Regions()
+		// Service endpoints include pseudo regions (e.g. fips-us-east-1) so only keep actual regions
+		for id := range s.Regions() {
+			if _, ok := known[id]; ok {
+				regions = append(regions, id)
+			}
+		}
+	}
+	return sortedUnique(regions)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+)
+
+// Result is the result set of a statement run with ExecuteAndWait
+type Result struct {
+	ID      string
+	Columns []*redshiftdataapiservice.ColumnMetadata
+	Records [][]*redshiftdataapiservice.Field
+	// Status is the final status of the statement, with its statistics
+	Status *StatementStatus
+	// Truncated is true if records were left out because of the MaxRows of the statement
+	Truncated bool
+}
+
+// ExecuteAndWait runs a statement, waits for it to finish and returns its result. The error tells the
+// step that failed: an api.ExecuteError when submitting it, a status, timeout or cancel error while waiting
+// (or the error of the statement itself if it fails), or a ResultError when reading the result.
+// Statements without a result set (e.g. VACUUM) return an empty result. The statement is canceled if the context is canceled or
+// the query timeout is reached while waiting. The retries of the calls made for the statement share the retry budget.
+func (c *API) ExecuteAndWait(ctx context.Context, input *StatementInput) (*Result, error) {
+	if err := c.checkStatementSettings(ctx, input); err != nil {
+		return nil, err
+	}
+	ctx = WithStatementCancellation(c.WithQueryRetryBudget(ctx))
+	output, err := c.ExecuteStatement(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	status, err := c.WaitOnQuery(ctx, output, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !status.HasResultSet {
+		return &Result{ID: output.ID, Columns: []*redshiftdataapiservice.ColumnMetadata{}, Records: [][]*redshiftdataapiservice.Field{}, Status: status}, nil
+	}
+	result, truncated, err := c.getResult(ctx, output.ID, input.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		ID:        output.ID,
+		Columns:   result.ColumnMetadata,
+		Records:   result.Records,
+		Status:    status,
+		Truncated: truncated,
+	}, nil
+}
+
+var (
+	selectQueryRegexp = regexp.MustCompile(`(?is)^\s*select\b`)
+	limitRegexp       = regexp.MustCompile(`(?i)\blimit\s+(\d+|all)\b`)
+)
+
+// LimitQuery adds a LIMIT of maxRows+1 to a SELECT query without one, so the cluster doesn't produce
+// more records than needed to tell if the result is truncated. Other queries are returned as is.
+func LimitQuery(query string, maxRows int) string {
+	trimmed := strings.TrimRight(strings.TrimSpace(query), ";")
+	// Queries with several statements are left alone
+	if maxRows <= 0 || !selectQueryRegexp.MatchString(trimmed) || limitRegexp.MatchString(trimmed) || strings.Contains(trimmed, ";") {
+		return query
+	}
+	// The LIMIT is on its own line in case the query ends with a comment
+	return fmt.Sprintf("%s\nLIMIT %d", trimmed, maxRows+1)
+}
+
+// ResultIterator reads the result of a statement one record at a time, fetching the pages when needed
+//
+//	it := c.Results(id)
+//	defer it.Close()
+//	for it.Next(ctx) {
+//		record := it.Record()
+//		...
+//	}
+//	err := it.Err()
+type ResultIterator struct {
+	api   *API
+	input *redshiftdataapiservice.GetStatementResultInput
+
+	columns []*redshiftdataapiservice.ColumnMetadata
+	page    [][]*redshiftdataapiservice.Field
+	record  []*redshiftdataapiservice.Field
+	fetched bool
+	done    bool
+	err     error
+}
+
+// Results returns an iterator over the result of the statement. No call is made until Next is called.
+func (c *API) Results(id string) *ResultIterator {
+	return &ResultIterator{
+		api:   c,
+		input: &redshiftdataapiservice.GetStatementResultInput{Id: aws.String(id)},
+	}
+}
+
+// Next moves to the next record, it returns false once every record has been read or if an error happened
+func (it *ResultIterator) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if it.done || (it.fetched && it.input.NextToken == nil) {
+			it.done = true
+			it.record = nil
+			return false
+		}
+		out, err := it.api.resultPage(ctx, it.input, !it.fetched)
+		if err != nil {
+			it.err = err
+			it.done = true
+			it.record = nil
+			return false
+		}
+		if !it.fetched {
+			it.columns = out.ColumnMetadata
+			it.fetched = true
+		}
+		it.page = out.Records
+		it.input.NextToken = out.NextToken
+		if aws.StringValue(out.NextToken) == "" {
+			it.input.NextToken = nil
+		}
+	}
+	it.record = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// Record returns the current record
+func (it *ResultIterator) Record() []*redshiftdataapiservice.Field {
+	return it.record
+}
+
+// Columns returns the metadata of the columns, it's available after the first call to Next
+func (it *ResultIterator) Columns() []*redshiftdataapiservice.ColumnMetadata {
+	return it.columns
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *ResultIterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration, the remaining pages are not fetched
+func (it *ResultIterator) Close() error {
+	it.done = true
+	it.page = nil
+	it.record = nil
+	return nil
+}
+
+// DecodeSuper parses the JSON value of a SUPER field into maps, slices and scalars.
+// Null fields are decoded as nil. Use the StringValue of the field to keep the raw JSON instead.
+func DecodeSuper(field *redshiftdataapiservice.Field) (interface{}, error) {
+	if field == nil || aws.BoolValue(field.IsNull) || field.StringValue == nil {
+		return nil, nil
+	}
+	var res interface{}
+	if err := json.Unmarshal([]byte(*field.StringValue), &res); err != nil {
+		return nil, fmt.Errorf("invalid SUPER value: %w", err)
+	}
+	return res, nil
+}
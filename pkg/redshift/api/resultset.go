@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+)
+
+// ResultColumn is a column of a ResultSet
+type ResultColumn struct {
+	Name string
+	// Type is the Redshift type of the column, e.g. int8 or varchar
+	Type     string
+	Nullable bool
+}
+
+// ResultSet is a statement result with plain Go values instead of the fields of the Data API.
+// The values are string, int64, float64, bool, []byte or nil for nulls.
+type ResultSet struct {
+	Columns []ResultColumn
+	Rows    [][]interface{}
+	// Truncated is true if rows were left out because of maxRows
+	Truncated bool
+}
+
+// GetResultSet fetches the result of the statement like GetResult and converts it to a ResultSet
+func (c *API) GetResultSet(ctx aws.Context, id string, maxRows int) (*ResultSet, error) {
+	out, truncated, err := c.getResult(ctx, id, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	res, err := NewResultSet(out.ColumnMetadata, out.Records)
+	if err != nil {
+		return nil, err
+	}
+	res.Truncated = truncated
+	return res, nil
+}
+
+// ResultSet converts the result of ExecuteAndWait
+func (r *Result) ResultSet() (*ResultSet, error) {
+	res, err := NewResultSet(r.Columns, r.Records)
+	if err != nil {
+		return nil, err
+	}
+	res.Truncated = r.Truncated
+	return res, nil
+}
+
+// NewResultSet converts the columns and records of a Data API result
+func NewResultSet(columns []*redshiftdataapiservice.ColumnMetadata, records [][]*redshiftdataapiservice.Field) (*ResultSet, error) {
+	res := &ResultSet{Columns: make([]ResultColumn, 0, len(columns)), Rows: make([][]interface{}, 0, len(records))}
+	for _, col := range columns {
+		if col == nil {
+			return nil, fmt.Errorf("%w: missing column metadata", ResultError)
+		}
+		res.Columns = append(res.Columns, ResultColumn{
+			Name: aws.StringValue(col.Name),
+			Type: aws.StringValue(col.TypeName),
+			// The nullability is unknown (2) for expressions, they may be null
+			Nullable: aws.Int64Value(col.Nullable) != 0,
+		})
+	}
+	for i, record := range records {
+		if len(record) != len(res.Columns) {
+			return nil, fmt.Errorf("%w: record %d has %d fields, expected %d", ResultError, i, len(record), len(res.Columns))
+		}
+		row := make([]interface{}, len(record))
+		for j, field := range record {
+			value, err := columnValue(res.Columns[j], field)
+			if err != nil {
+				return nil, fmt.Errorf("%w: column %s of record %d: %v", ResultError, res.Columns[j].Name, i, err)
+			}
+			row[j] = value
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return res, nil
+}
+
+// integerTypes are the names of the integer column types, as reported by the Data API or their aliases
+var integerTypes = map[string]bool{"int2": true, "int4": true, "int8": true, "int": true, "integer": true, "smallint": true, "bigint": true}
+
+// columnValue returns the value of the field, an int64 for the integer columns
+func columnValue(column ResultColumn, field *redshiftdataapiservice.Field) (interface{}, error) {
+	if !integerTypes[strings.ToLower(column.Type)] || field == nil || aws.BoolValue(field.IsNull) {
+		return FieldValue(field)
+	}
+	return IntegerValue(field)
+}
+
+// IntegerValue returns the value of a field of an integer column. The Data API sets longValue, a string value
+// is still parsed as an integer rather than as a float so that BIGINT values above 2^53 stay exact.
+func IntegerValue(field *redshiftdataapiservice.Field) (int64, error) {
+	switch {
+	case field.LongValue != nil:
+		return *field.LongValue, nil
+	case field.StringValue != nil:
+		return strconv.ParseInt(*field.StringValue, 10, 64)
+	}
+	return 0, fmt.Errorf("integer field without a value")
+}
+
+// FieldValue returns the value of the field set by the Data API, nil for a null
+func FieldValue(field *redshiftdataapiservice.Field) (interface{}, error) {
+	switch {
+	case field == nil || aws.BoolValue(field.IsNull):
+		return nil, nil
+	case field.StringValue != nil:
+		return *field.StringValue, nil
+	case field.LongValue != nil:
+		return *field.LongValue, nil
+	case field.DoubleValue != nil:
+		return *field.DoubleValue, nil
+	case field.BooleanValue != nil:
+		return *field.BooleanValue, nil
+	case field.BlobValue != nil:
+		return field.BlobValue, nil
+	}
+	return nil, fmt.Errorf("field without a value")
+}
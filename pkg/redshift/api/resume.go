@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshift"
+)
+
+const defaultAutoResumeTimeout = 10 * time.Minute
+
+// resumePollInterval is the delay between the checks of a resuming cluster
+var resumePollInterval = 15 * time.Second
+
+// resumeCluster resumes the paused cluster and waits until it's available, or the auto resume timeout.
+// Serverless workgroups resume by themselves so they don't need it.
+func (c *API) resumeCluster(ctx aws.Context) error {
+	settings := c.overriddenSettings(ctx)
+	id := settings.ClusterIdentifier
+	c.logger().Debug("resuming the paused cluster", "cluster", id)
+	c.metrics().IncCall("ResumeCluster")
+	_, err := c.ManagementClient.ResumeClusterWithContext(ctx, &redshift.ResumeClusterInput{ClusterIdentifier: aws.String(id)})
+	// The cluster may be resumed already, e.g. by another query
+	if err != nil && awsErrorCode(err) != redshift.ErrCodeInvalidClusterStateFault {
+		return c.resumeError(ctx, err)
+	}
+
+	timeout := defaultAutoResumeTimeout
+	if c.settings.AutoResumeTimeout > 0 {
+		timeout = time.Duration(c.settings.AutoResumeTimeout) * time.Second
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		c.metrics().IncCall("DescribeClusters")
+		out, err := c.ManagementClient.DescribeClustersWithContext(ctx, &redshift.DescribeClustersInput{ClusterIdentifier: aws.String(id)})
+		if err != nil {
+			return c.resumeError(ctx, err)
+		}
+		if len(out.Clusters) > 0 && aws.StringValue(out.Clusters[0].ClusterStatus) == "available" {
+			c.logger().Debug("cluster resumed", "cluster", id)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", CanceledError, ctx.Err())
+		case <-deadline.C:
+			return fmt.Errorf("%w: the cluster is still not available after %s", ClusterResumeError, timeout)
+		case <-time.After(resumePollInterval):
+		}
+	}
+}
+
+func (c *API) resumeError(ctx aws.Context, err error) error {
+	if isCanceled(ctx, err) {
+		return fmt.Errorf("%w: %v", CanceledError, err)
+	}
+	wrapped := fmt.Errorf("%w: %v", ClusterResumeError, err)
+	if isPermissionError(err) {
+		// The resume is opt-in, so the permissions it needs are named even if the AWS error doesn't
+		action := "redshift:ResumeCluster and redshift:DescribeClusters"
+		if m := deniedActionRegexp.FindStringSubmatch(err.Error()); m != nil {
+			action = m[1]
+		}
+		return &PermissionError{Action: action, Err: wrapped}
+	}
+	return wrapped
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/jpillora/backoff"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultRetryDelay = 200 * time.Millisecond
+	// defaultRetryBudget is the number of retries shared by the calls made for a query
+	defaultRetryBudget = 10
+	maxRetryDelay      = 10 * time.Second
+)
+
+// isThrottlingError returns true for errors caused by the Data API limits, which are worth retrying
+func isThrottlingError(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	return request.IsErrorThrottle(awsErr) || awsErr.Code() == redshiftdataapiservice.ErrCodeActiveStatementsExceededException
+}
+
+// isRetryableError returns true for throttling errors and for calls made while the cluster is resuming
+func isRetryableError(err error) bool {
+	return isThrottlingError(err) || isClusterResumingError(err)
+}
+
+// isTransientError returns true for network errors and server side failures, which may succeed if retried
+func isTransientError(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	return request.IsErrorRetryable(awsErr) || awsErr.Code() == redshiftdataapiservice.ErrCodeInternalServerException
+}
+
+// retryBudget is the number of retries left to the calls made for a query
+type retryBudget struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+// take returns true if there is a retry left, consuming it
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+type retryBudgetKey struct{}
+
+// WithRetryBudget limits the total number of retries of the calls made with the context, e.g. to
+// submit a statement, poll its status and fetch its result, on top of the retries of each call.
+func WithRetryBudget(ctx context.Context, retries int) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, &retryBudget{remaining: retries})
+}
+
+// WithQueryRetryBudget returns a context with the configured retry budget for a query, unless it has one already
+func (c *API) WithQueryRetryBudget(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(retryBudgetKey{}).(*retryBudget); ok {
+		return ctx
+	}
+	budget := defaultRetryBudget
+	if c.settings != nil && c.settings.RetryBudget != 0 {
+		budget = c.settings.RetryBudget
+	}
+	if budget < 0 {
+		return ctx
+	}
+	return WithRetryBudget(ctx, budget)
+}
+
+// retryAfterError is a throttling error with the delay the service asked to wait before retrying
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// retryAfter returns a request option storing the delay of the Retry-After header of the response, if any
+func retryAfter(delay *time.Duration) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			if r.HTTPResponse == nil {
+				return
+			}
+			if seconds, err := strconv.Atoi(r.HTTPResponse.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				*delay = time.Duration(seconds) * time.Second
+			}
+		})
+	}
+}
+
+// withRetryAfter attaches the delay read by retryAfter to the error, so the retry waits at least that long
+func withRetryAfter(err error, delay time.Duration) error {
+	if err == nil || delay <= 0 {
+		return err
+	}
+	return &retryAfterError{err: err, delay: delay}
+}
+
+// withRetry calls fn until it succeeds, it returns an error that cannot be retried or the retries are exhausted.
+// Retries are delayed with an exponential backoff with jitter. Every attempt is measured as a call to the operation.
+func (c *API) withRetry(ctx aws.Context, operation string, fn func(aws.Context) error) error {
+	return c.retry(ctx, operation, isRetryableError, fn)
+}
+
+// withTransientRetry is withRetry also retrying transient errors, for calls that are safe to repeat
+func (c *API) withTransientRetry(ctx aws.Context, operation string, fn func(aws.Context) error) error {
+	return c.retry(ctx, operation, func(err error) bool {
+		return isRetryableError(err) || isTransientError(err)
+	}, fn)
+}
+
+// withTimeout calls fn with a context limited to the call timeout of the settings, if any. It's
+// independent of the deadline of ctx so a single slow call, e.g. a page of a huge catalog, can't stall a request.
+func (c *API) withTimeout(ctx aws.Context, operation string, fn func(aws.Context) error) error {
+	if c.settings == nil || c.settings.CallTimeout <= 0 {
+		return fn(ctx)
+	}
+	timeout := time.Duration(c.settings.CallTimeout) * time.Second
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := fn(callCtx)
+	// The error is only a timeout if the outer context is still alive
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s did not complete within %s", CallTimeoutError, operation, timeout)
+	}
+	return err
+}
+
+func (c *API) retry(ctx aws.Context, operation string, retryable func(error) bool, fn func(aws.Context) error) error {
+	maxRetries, delay := defaultMaxRetries, defaultRetryDelay
+	if c.settings != nil {
+		if c.settings.MaxRetries > 0 {
+			maxRetries = c.settings.MaxRetries
+		} else if c.settings.MaxRetries < 0 {
+			maxRetries = 0
+		}
+		if c.settings.RetryDelay > 0 {
+			delay = time.Duration(c.settings.RetryDelay) * time.Millisecond
+		}
+	}
+	b := backoff.Backoff{
+		Min:    delay,
+		Max:    maxRetryDelay,
+		Factor: 2,
+		Jitter: true,
+	}
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err := c.withTimeout(ctx, operation, fn)
+		// The delay asked by the service only changes the wait, the error is returned as is
+		minDelay := time.Duration(0)
+		var hint *retryAfterError
+		if errors.As(err, &hint) {
+			err, minDelay = hint.err, hint.delay
+		}
+		c.metrics().IncCall(operation)
+		c.metrics().ObserveDuration(operation, time.Since(start))
+		if err != nil && isThrottlingError(err) {
+			c.metrics().IncThrottle(operation)
+		}
+		if err == nil || !retryable(err) || attempt >= maxRetries {
+			return err
+		}
+		if budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget); ok && !budget.take() {
+			c.logger().Debug("retry budget of the query exhausted", "operation", operation)
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay(b.Duration(), minDelay)):
+		}
+	}
+}
+
+// retryDelay returns the backoff delay, or the delay asked by the service if it's longer, up to the maximum delay
+func retryDelay(backoff time.Duration, minDelay time.Duration) time.Duration {
+	if minDelay <= backoff {
+		return backoff
+	}
+	if minDelay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return minDelay
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/sql/api"
+)
+
+// UnknownRowCount is the estimate of the tables whose statistics can't be read
+const UnknownRowCount int64 = -1
+
+// rowCountQuery reads the estimate of the statistics, svv_table_info only has the tables with data the user can see
+const rowCountQuery = `SELECT tbl_rows::bigint FROM svv_table_info WHERE "schema" = :schema AND "table" = :table`
+
+// EstimateRowCount returns the approximate number of rows of a table according to its statistics, without
+// scanning it. It returns UnknownRowCount if the table has no statistics or if the catalog can't be read.
+// The estimates are cached like the tables since they change slowly.
+func (c *API) EstimateRowCount(ctx context.Context, schema, table string) (int64, error) {
+	if schema == "" {
+		schema = c.tablesSchema(nil)
+	}
+	schema, table = identifierName(schema), identifierName(table)
+	if table == "" {
+		return UnknownRowCount, fmt.Errorf("missing table")
+	}
+	key := c.cacheKey(ctx, "rowcount", schema, table)
+	if count, ok := c.cache.getCount(key); ok {
+		return count, nil
+	}
+
+	res, err := c.ExecuteAndWait(ctx, &StatementInput{
+		ExecuteQueryInput: api.ExecuteQueryInput{Query: rowCountQuery},
+		Parameters:        map[string]string{"schema": schema, "table": table},
+	})
+	if err != nil {
+		if isCatalogDenied(err) {
+			c.logger().Debug("unable to read the statistics of the table", "schema", schema, "table", table, "error", err.Error())
+			return UnknownRowCount, nil
+		}
+		return UnknownRowCount, err
+	}
+	count := UnknownRowCount
+	if len(res.Records) > 0 && len(res.Records[0]) > 0 {
+		value, err := FieldValue(res.Records[0][0])
+		if err != nil {
+			return UnknownRowCount, fmt.Errorf("%w: %v", ResultError, err)
+		}
+		switch v := value.(type) {
+		case int64:
+			count = v
+		case string:
+			if count, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return UnknownRowCount, fmt.Errorf("%w: invalid row count %q", ResultError, v)
+			}
+		}
+	}
+	c.cache.setCount(key, count)
+	return count, nil
+}
+
+// isCatalogDenied returns true if the IAM role can't run the query or the database user can't read the catalog
+func isCatalogDenied(err error) bool {
+	var permErr *PermissionError
+	if errors.As(err, &permErr) {
+		return true
+	}
+	var statementErr *StatementError
+	return errors.As(err, &statementErr) && strings.Contains(strings.ToLower(statementErr.Message), "permission denied")
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// flightGroup coalesces the concurrent calls listing the same resources, e.g. the schemas requested by
+// every panel of a dashboard when it's opened, into a single call whose result is shared by all the callers.
+// The zero value is ready to use and it's safe for concurrent use.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done  chan struct{}
+	value []string
+	err   error
+	// abandoned is set if the call failed once the context of its caller was done, canceled or past its deadline
+	abandoned bool
+}
+
+// do calls fn unless a call with the same key is in flight, in which case it waits for its result. The key
+// must identify the credentials as well, like the cache keys. A caller that stops waiting because its context
+// is done doesn't affect the others, and if the call fails once the context of its caller is canceled or
+// past its deadline, the callers still waiting make it again instead of sharing an error that isn't theirs.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]string, error)) ([]string, error) {
+	for {
+		g.mu.Lock()
+		if g.calls == nil {
+			g.calls = map[string]*flight{}
+		}
+		if f, ok := g.calls[key]; ok {
+			g.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				if isCanceled(ctx, ctx.Err()) {
+					return nil, fmt.Errorf("%w: %v", CanceledError, ctx.Err())
+				}
+				return nil, ctx.Err()
+			case <-f.done:
+			}
+			if ctx.Err() == nil && f.abandoned {
+				continue
+			}
+			res := f.value
+			if res != nil {
+				// The callers may modify their result
+				res = append([]string{}, res...)
+			}
+			return res, f.err
+		}
+		f := &flight{done: make(chan struct{})}
+		g.calls[key] = f
+		g.mu.Unlock()
+
+		func() {
+			defer func() {
+				g.mu.Lock()
+				delete(g.calls, key)
+				g.mu.Unlock()
+				close(f.done)
+			}()
+			f.value, f.err = fn()
+			// The errors of the lists don't keep the error of the context, check it directly
+			f.abandoned = f.err != nil && ctx.Err() != nil
+		}()
+		return f.value, f.err
+	}
+}
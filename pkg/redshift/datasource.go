@@ -60,13 +60,34 @@ func (s *RedshiftDatasource) Connect(config backend.DataSourceInstanceSettings,
 }
 
 func (s *RedshiftDatasource) getApi(ctx context.Context, options sqlds.Options) (*api.API, error) {
+	return s.loadApi(ctx, options, api.New)
+}
+
+// configAPIOption keeps the APIs of the configuration page apart from the ones of the queries in the
+// cache of the datasource, which is keyed by the options, since they are created differently
+const configAPIOption = "configAPI"
+
+// getConfigApi returns an API for the resources used by the configuration page, which can be
+// requested before the settings are valid
+func (s *RedshiftDatasource) getConfigApi(ctx context.Context, options sqlds.Options) (*api.API, error) {
+	configOptions := sqlds.Options{configAPIOption: "true"}
+	for k, v := range options {
+		configOptions[k] = v
+	}
+	return s.loadApi(ctx, configOptions, api.NewConfigAPI)
+}
+
+func (s *RedshiftDatasource) loadApi(ctx context.Context, options sqlds.Options, loader sqlAPI.Loader) (*api.API, error) {
 	id := datasource.GetDatasourceID(ctx)
-	res, err := s.awsDS.GetAPI(id, options, models.New, api.New)
-	return res.(*api.API), err
+	res, err := s.awsDS.GetAPI(id, options, models.New, loader)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*api.API), nil
 }
 
 func (s *RedshiftDatasource) Regions(ctx context.Context) ([]string, error) {
-	api, err := s.getApi(ctx, sqlds.Options{})
+	api, err := s.getConfigApi(ctx, sqlds.Options{})
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +147,7 @@ func (s *RedshiftDatasource) Columns(ctx context.Context, options sqlds.Options)
 }
 
 func (s *RedshiftDatasource) Secrets(ctx context.Context, options sqlds.Options) ([]models.ManagedSecret, error) {
-	api, err := s.getApi(ctx, options)
+	api, err := s.getConfigApi(ctx, options)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +155,8 @@ func (s *RedshiftDatasource) Secrets(ctx context.Context, options sqlds.Options)
 }
 
 func (s *RedshiftDatasource) Secret(ctx context.Context, options sqlds.Options) (*models.RedshiftSecret, error) {
-	api, err := s.getApi(ctx, options)
+	// The ARN is the secret to read, not an option of the API, so every secret shares the same API
+	api, err := s.getConfigApi(ctx, sqlds.Options{})
 	if err != nil {
 		return nil, err
 	}
@@ -142,7 +164,7 @@ func (s *RedshiftDatasource) Secret(ctx context.Context, options sqlds.Options)
 }
 
 func (s *RedshiftDatasource) Clusters(ctx context.Context, options sqlds.Options) ([]models.RedshiftCluster, error) {
-	api, err := s.getApi(ctx, options)
+	api, err := s.getConfigApi(ctx, options)
 	if err != nil {
 		return nil, err
 	}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	sqlAPI "github.com/grafana/grafana-aws-sdk/pkg/sql/api"
 	"github.com/grafana/redshift-datasource/pkg/redshift/api"
 )
 
 type conn struct {
-	api    *api.API
-	closed bool
+	api *api.API
+	// closed is set atomically since the driver checks it while the connection is used
+	closed int32
 }
 
 func newConnection(api *api.API) *conn {
@@ -19,25 +22,58 @@ func newConnection(api *api.API) *conn {
 }
 
 func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	output, err := c.api.Execute(ctx, &sqlAPI.ExecuteQueryInput{Query: query})
+	params, err := namedParameters(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.api.CheckSettings(ctx); err != nil {
+		return nil, err
+	}
+	settings := c.api.Settings()
+	if settings.LimitQueries {
+		query = api.LimitQuery(query, settings.MaxRows)
+	}
+	ctx = api.WithStatementCancellation(c.api.WithQueryRetryBudget(ctx))
+	output, err := c.api.ExecuteStatement(ctx, &api.StatementInput{
+		ExecuteQueryInput: sqlAPI.ExecuteQueryInput{Query: query},
+		Parameters:        params,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err := sqlAPI.WaitOnQuery(ctx, c.api, output); err != nil {
+	status, err := c.api.WaitOnQuery(ctx, output, 0)
+	if err != nil {
 		return nil, err
 	}
+	if !status.HasResultSet {
+		// Statements like VACUUM or COPY don't have a result to fetch
+		return newEmptyRows(), nil
+	}
 
-	return newRows(c.api.DataClient, output.ID)
+	// The time zone is validated with the settings
+	location, _ := time.LoadLocation(settings.TimestampTimeZone)
+	return newRows(ctx, c.api, output.ID, rowOptions{
+		decimalAsString: settings.DecimalAsString,
+		location:        location,
+		maxRows:         settings.MaxRows,
+	})
 }
 
-func (c *conn) Ping(ctx context.Context) error {
-	rows, err := c.QueryContext(ctx, "SELECT 1", nil)
-	if err != nil {
-		return err
+// namedParameters converts the query arguments to Data API parameters, which can only be bound by name
+func namedParameters(args []driver.NamedValue) (map[string]string, error) {
+	params := map[string]string{}
+	for _, arg := range args {
+		if arg.Name == "" {
+			return nil, fmt.Errorf("redshift driver only supports named parameters")
+		}
+		params[arg.Name] = fmt.Sprint(arg.Value)
 	}
-	defer rows.Close()
-	return nil
+	return params, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return c.api.HealthCheck(ctx)
 }
 
 func (c *conn) Begin() (driver.Tx, error) {
@@ -49,6 +85,10 @@ func (c *conn) Prepare(query string) (driver.Stmt, error) {
 }
 
 func (c *conn) Close() error {
-	c.closed = true
+	atomic.StoreInt32(&c.closed, 1)
 	return nil
 }
+
+func (c *conn) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
@@ -21,19 +21,26 @@ var (
 
 // Driver is a sql.Driver
 type Driver struct {
-	name       string
-	api        *api.API
+	name string
+	api  *api.API
+	// database/sql opens connections concurrently, mu guards the last one
+	mu         sync.Mutex
 	connection *conn
 }
 
 // Open returns a new driver.Conn using already existing settings
 func (d *Driver) Open(_ string) (driver.Conn, error) {
-	d.connection = newConnection(d.api)
-	return d.connection, nil
+	c := newConnection(d.api)
+	d.mu.Lock()
+	d.connection = c
+	d.mu.Unlock()
+	return c, nil
 }
 
 func (d *Driver) Closed() bool {
-	return d.connection == nil || d.connection.closed
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.connection == nil || d.connection.isClosed()
 }
 
 func (d *Driver) OpenDB() (*sql.DB, error) {
@@ -0,0 +1,129 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/redshift-datasource/pkg/redshift/api"
+	"github.com/grafana/redshift-datasource/pkg/redshift/models"
+	"github.com/grafana/sqlds/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentConnections is meant to be run with the race detector, go test -race
+func TestConcurrentConnections(t *testing.T) {
+	d := &Driver{api: &api.API{}, name: DriverName}
+	require.True(t, d.Closed())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := d.Open("")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			d.Closed()
+			_ = c.Close()
+		}()
+	}
+	wg.Wait()
+	require.True(t, d.Closed())
+}
+
+// statelessService answers the calls of a query and of the lists the same way every time, so it can be
+// used concurrently and the race detector only checks the driver and the API
+type statelessService struct {
+	redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
+}
+
+func (s *statelessService) ExecuteStatementWithContext(aws.Context, *redshiftdataapiservice.ExecuteStatementInput, ...request.Option) (*redshiftdataapiservice.ExecuteStatementOutput, error) {
+	return &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("foo")}, nil
+}
+
+func (s *statelessService) DescribeStatementWithContext(aws.Context, *redshiftdataapiservice.DescribeStatementInput, ...request.Option) (*redshiftdataapiservice.DescribeStatementOutput, error) {
+	return &redshiftdataapiservice.DescribeStatementOutput{Id: aws.String("foo"), Status: aws.String(redshiftdataapiservice.StatusStringFinished), HasResultSet: aws.Bool(true)}, nil
+}
+
+func (s *statelessService) GetStatementResultWithContext(aws.Context, *redshiftdataapiservice.GetStatementResultInput, ...request.Option) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	return &redshiftdataapiservice.GetStatementResultOutput{
+		ColumnMetadata: []*redshiftdataapiservice.ColumnMetadata{{Name: aws.String("one"), TypeName: aws.String("int4")}},
+		Records:        [][]*redshiftdataapiservice.Field{{{LongValue: aws.Int64(1)}}},
+	}, nil
+}
+
+func (s *statelessService) ListSchemasWithContext(aws.Context, *redshiftdataapiservice.ListSchemasInput, ...request.Option) (*redshiftdataapiservice.ListSchemasOutput, error) {
+	return &redshiftdataapiservice.ListSchemasOutput{Schemas: aws.StringSlice([]string{"public", "sales"})}, nil
+}
+
+func (s *statelessService) ListTablesWithContext(aws.Context, *redshiftdataapiservice.ListTablesInput, ...request.Option) (*redshiftdataapiservice.ListTablesOutput, error) {
+	return &redshiftdataapiservice.ListTablesOutput{Tables: []*redshiftdataapiservice.TableMember{{Name: aws.String("orders")}}}, nil
+}
+
+// TestConcurrentQueriesAndLists is meant to be run with the race detector, go test -race
+func TestConcurrentQueriesAndLists(t *testing.T) {
+	res, err := api.NewRedshiftAPI(awsds.NewSessionCache(), &models.RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"})
+	require.NoError(t, err)
+	dsAPI := res.(*api.API)
+	dsAPI.DataClient = &statelessService{}
+	c := newConnection(dsAPI)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			schemas, err := dsAPI.Schemas(context.Background(), sqlds.Options{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(schemas) != 2 {
+				t.Errorf("unexpected schemas %v", schemas)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			tables, err := dsAPI.Tables(context.Background(), sqlds.Options{"schema": "public"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(tables) != 1 {
+				t.Errorf("unexpected tables %v", tables)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// A cancelable context tracks the statement while it's in flight
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			rows, err := c.QueryContext(ctx, "SELECT 1", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer rows.Close()
+			values := make([]driver.Value, len(rows.Columns()))
+			for {
+				if err := rows.Next(values); err != nil {
+					if err != io.EOF {
+						t.Error(err)
+					}
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
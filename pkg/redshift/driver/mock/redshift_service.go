@@ -44,6 +44,8 @@ var twoRecords = [][]*redshiftdataapiservice.Field{
 type RedshiftService struct {
 	CalledTimesCounter   int
 	CalledTimesCountDown int
+	// ResultErrors are returned by the first calls to GetStatementResultWithContext
+	ResultErrors []error
 }
 
 func NewMockRedshiftService() *RedshiftService {
@@ -87,6 +89,7 @@ func (s *RedshiftService) DescribeStatementWithContext(_ aws.Context, input *red
 			output.Error = aws.String(DESCRIBE_STATEMENT_FAILED)
 		} else {
 			output.Status = aws.String(redshiftdataapiservice.StatusStringFinished)
+			output.HasResultSet = aws.Bool(true)
 		}
 	} else {
 		output.Status = aws.String(redshiftdataapiservice.StatusStringStarted)
@@ -94,6 +97,18 @@ func (s *RedshiftService) DescribeStatementWithContext(_ aws.Context, input *red
 	return output, nil
 }
 
+func (s *RedshiftService) BatchExecuteStatement(*redshiftdataapiservice.BatchExecuteStatementInput) (*redshiftdataapiservice.BatchExecuteStatementOutput, error) {
+	panic("not implemented")
+}
+
+func (s *RedshiftService) BatchExecuteStatementWithContext(aws.Context, *redshiftdataapiservice.BatchExecuteStatementInput, ...request.Option) (*redshiftdataapiservice.BatchExecuteStatementOutput, error) {
+	panic("not implemented")
+}
+
+func (s *RedshiftService) BatchExecuteStatementRequest(*redshiftdataapiservice.BatchExecuteStatementInput) (*request.Request, *redshiftdataapiservice.BatchExecuteStatementOutput) {
+	panic("not implemented")
+}
+
 func (s *RedshiftService) CancelStatement(*redshiftdataapiservice.CancelStatementInput) (*redshiftdataapiservice.CancelStatementOutput, error) {
 	panic("not implemented")
 }
@@ -146,8 +161,14 @@ func (s *RedshiftService) ExecuteStatementRequest(*redshiftdataapiservice.Execut
 	panic("not implemented")
 }
 
-func (s *RedshiftService) GetStatementResultWithContext(aws.Context, *redshiftdataapiservice.GetStatementResultInput, ...request.Option) (*redshiftdataapiservice.GetStatementResultOutput, error) {
-	panic("not implemented")
+// GetStatementResultWithContext returns the first of ResultErrors, if any, or the result of GetStatementResult
+func (s *RedshiftService) GetStatementResultWithContext(_ aws.Context, input *redshiftdataapiservice.GetStatementResultInput, _ ...request.Option) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	if len(s.ResultErrors) > 0 {
+		err := s.ResultErrors[0]
+		s.ResultErrors = s.ResultErrors[1:]
+		return nil, err
+	}
+	return s.GetStatementResult(input)
 }
 
 func (s *RedshiftService) GetStatementResultRequest(*redshiftdataapiservice.GetStatementResultInput) (*request.Request, *redshiftdataapiservice.GetStatementResultOutput) {
@@ -10,6 +10,7 @@ const (
 	REDSHIFT_FLOAT                    = "FLOAT"
 	REDSHIFT_FLOAT8                   = "FLOAT8"
 	REDSHIFT_BOOL                     = "BOOL"
+	REDSHIFT_BOOLEAN                  = "BOOLEAN"
 	REDSHIFT_CHARACTER                = "CHARACTER"
 	REDSHIFT_NCHAR                    = "NCHAR"
 	REDSHIFT_BPCHAR                   = "BPCHAR"
@@ -26,4 +27,7 @@ const (
 	REDSHIFT_HLLSKETCH                = "HLLSKETCH"
 	REDSHIFT_SUPER                    = "SUPER"
 	REDSHIFT_NAME                     = "NAME"
+	REDSHIFT_VARBYTE                  = "VARBYTE"
+	REDSHIFT_VARBINARY                = "VARBINARY"
+	REDSHIFT_BINARY_VARYING           = "BINARY VARYING"
 )
@@ -1,7 +1,9 @@
 package driver
 
 import (
+	"context"
 	"database/sql/driver"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"reflect"
@@ -11,22 +13,38 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
-	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/redshift-datasource/pkg/redshift/api"
 )
 
+// rowOptions tune the conversion of the values
+type rowOptions struct {
+	// decimalAsString keeps the NUMERIC values as strings, which don't lose precision as floats do
+	decimalAsString bool
+	// location is the time zone of the TIMESTAMP values, which have none. UTC if nil.
+	location *time.Location
+	// maxRows stops the iteration after that number of rows, if greater than 0
+	maxRows int
+}
+
 type Rows struct {
-	service redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
+	api *api.API
+	// ctx is the context of the query, the pages are fetched with it until the rows are closed
+	ctx     context.Context
 	queryID string
+	options rowOptions
 
 	done   bool
 	result *redshiftdataapiservice.GetStatementResultOutput
+	rows   int
 }
 
-func newRows(service redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI, queryId string) (*Rows, error) {
+func newRows(ctx context.Context, dsAPI *api.API, queryId string, options rowOptions) (*Rows, error) {
 	r := Rows{
-		service: service,
+		api:     dsAPI,
+		ctx:     ctx,
 		queryID: queryId,
+		options: options,
 	}
 
 	if err := r.fetchNextPage(nil); err != nil {
@@ -36,6 +54,14 @@ func newRows(service redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI, quer
 	return &r, nil
 }
 
+// newEmptyRows returns the rows of a statement without a result set
+func newEmptyRows() *Rows {
+	return &Rows{
+		done:   true,
+		result: &redshiftdataapiservice.GetStatementResultOutput{},
+	}
+}
+
 // Next is called to populate the next row of data into
 // the provided slice. The provided slice will be the same
 // size as the Columns() are wide. io.EOF should be returned when there are no more rows.
@@ -44,6 +70,16 @@ func (r *Rows) Next(dest []driver.Value) error {
 		return io.EOF
 	}
 
+	if r.options.maxRows > 0 && r.rows >= r.options.maxRows {
+		// The remaining pages are not fetched. The frames are built by sqlds through database/sql,
+		// which can't tell the panel about the truncation, so it's only logged.
+		if len(r.result.Records) > 0 || aws.StringValue(r.result.NextToken) != "" {
+			backend.Logger.Warn("query result truncated", "query ID", r.queryID, "max rows", r.options.maxRows)
+		}
+		r.done = true
+		return io.EOF
+	}
+
 	// If nothing left to iterate...
 	if len(r.result.Records) == 0 {
 		// And if nothing more to paginate...
@@ -60,11 +96,12 @@ func (r *Rows) Next(dest []driver.Value) error {
 
 	// Shift to next row
 	current := r.result.Records[0]
-	if err := convertRow(r.result.ColumnMetadata, current, dest); err != nil {
+	if err := convertRow(r.result.ColumnMetadata, current, dest, r.options); err != nil {
 		return err
 	}
 
 	r.result.Records = r.result.Records[1:]
+	r.rows++
 	return nil
 }
 
@@ -103,6 +140,10 @@ func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
 		}
 	}
 
+	if strings.ToUpper(*col.TypeName) == REDSHIFT_NUMERIC && r.options.decimalAsString {
+		return reflect.TypeOf("")
+	}
+
 	switch strings.ToUpper(*col.TypeName) {
 	case REDSHIFT_INT2:
 		return reflect.TypeOf(int16(0))
@@ -114,7 +155,8 @@ func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
 		return reflect.TypeOf(float32(0))
 	case REDSHIFT_NUMERIC, REDSHIFT_FLOAT, REDSHIFT_FLOAT8:
 		return reflect.TypeOf(float64(0))
-	case REDSHIFT_BOOL:
+	case REDSHIFT_BOOL, REDSHIFT_BOOLEAN:
+		// It's mapped to a nullable bool field, NULL values included
 		return reflect.TypeOf(false)
 	case REDSHIFT_CHARACTER,
 		REDSHIFT_VARCHAR,
@@ -124,6 +166,9 @@ func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
 		REDSHIFT_NVARCHAR,
 		REDSHIFT_TEXT:
 		return reflect.TypeOf("")
+	case REDSHIFT_VARBYTE, REDSHIFT_VARBINARY, REDSHIFT_BINARY_VARYING:
+		// Binary values are returned base64 encoded
+		return reflect.TypeOf("")
 	case REDSHIFT_TIMESTAMP,
 		REDSHIFT_TIMESTAMP_WITH_TIME_ZONE,
 		REDSHIFT_TIME_WITHOUT_TIME_ZONE,
@@ -146,6 +191,7 @@ func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
 		REDSHIFT_FLOAT8:                   "DOUBLE",
 		REDSHIFT_FLOAT:                    "DOUBLE",
 		REDSHIFT_BOOL:                     "BOOLEAN",
+		REDSHIFT_BOOLEAN:                  "BOOLEAN",
 		REDSHIFT_CHARACTER:                "CHAR",
 		REDSHIFT_NCHAR:                    "CHAR",
 		REDSHIFT_BPCHAR:                   "CHAR",
@@ -159,9 +205,14 @@ func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
 		REDSHIFT_TIME_WITHOUT_TIME_ZONE:   "TIME",
 		REDSHIFT_TIME_WITH_TIME_ZONE:      "TIMETZ",
 		REDSHIFT_GEOMETRY:                 "GEOMETRY",
-		// HLLSKETCH and SUPER are redshift specific types
+		// HLLSKETCH is a redshift specific type
 		REDSHIFT_HLLSKETCH: "VARCHAR",
-		REDSHIFT_SUPER:     "VARCHAR",
+		// SUPER values are returned as JSON strings, the name is kept so they can be told apart
+		REDSHIFT_SUPER: "SUPER",
+		// VARBYTE values are returned base64 encoded, the name is kept so they can be decoded
+		REDSHIFT_VARBYTE:        "VARBYTE",
+		REDSHIFT_VARBINARY:      "VARBYTE",
+		REDSHIFT_BINARY_VARYING: "VARBYTE",
 	}
 
 	typeName := strings.ToUpper(*r.result.ColumnMetadata[index].TypeName)
@@ -179,14 +230,12 @@ func (r *Rows) Close() error {
 	return nil
 }
 
-// fetchNextPage fetches the next statement result page and adds the result to the row
+// fetchNextPage fetches the next statement result page and adds the result to the row. The page is
+// fetched by the API, which retries the transient failures and maps the errors.
 func (r *Rows) fetchNextPage(token *string) error {
 	var err error
 
-	r.result, err = r.service.GetStatementResult(&redshiftdataapiservice.GetStatementResultInput{
-		Id:        aws.String(r.queryID),
-		NextToken: token,
-	})
+	r.result, err = r.api.ResultPage(r.ctx, r.queryID, token)
 
 	if err != nil {
 		return err
@@ -198,7 +247,7 @@ func (r *Rows) fetchNextPage(token *string) error {
 // convertRow converts values in a redshift data api row into its corresponding type in Go. Mapping is based on:
 // https://docs.aws.amazon.com/redshift/latest/dg/c_Supported_data_types.html
 // https://docs.aws.amazon.com/redshift/latest/mgmt/jdbc20-data-type-mapping.html
-func convertRow(columns []*redshiftdataapiservice.ColumnMetadata, data []*redshiftdataapiservice.Field, ret []driver.Value) error {
+func convertRow(columns []*redshiftdataapiservice.ColumnMetadata, data []*redshiftdataapiservice.Field, ret []driver.Value, options rowOptions) error {
 	for i, curr := range data {
 		if curr.IsNull != nil && *curr.IsNull {
 			ret[i] = nil
@@ -211,17 +260,26 @@ func convertRow(columns []*redshiftdataapiservice.ColumnMetadata, data []*redshi
 		}
 		typeName := strings.ToUpper(*col.TypeName)
 		switch typeName {
-		case REDSHIFT_INT2:
-			ret[i] = int16(*curr.LongValue)
-		case REDSHIFT_INT, REDSHIFT_INT4:
-			if *col.Name == "time" {
-				ret[i] = time.Unix(*curr.LongValue, 0).UTC()
-			} else {
-				ret[i] = int32(*curr.LongValue)
+		case REDSHIFT_INT2, REDSHIFT_INT, REDSHIFT_INT4, REDSHIFT_INT8:
+			v, err := api.IntegerValue(curr)
+			if err != nil {
+				return fmt.Errorf("invalid value of integer column %s: %w", aws.StringValue(col.Name), err)
+			}
+			switch {
+			case typeName == REDSHIFT_INT2:
+				ret[i] = int16(v)
+			case typeName == REDSHIFT_INT8:
+				ret[i] = v
+			case aws.StringValue(col.Name) == "time":
+				ret[i] = time.Unix(v, 0).UTC()
+			default:
+				ret[i] = int32(v)
 			}
-		case REDSHIFT_INT8:
-			ret[i] = *curr.LongValue
 		case REDSHIFT_NUMERIC, REDSHIFT_FLOAT, REDSHIFT_FLOAT4:
+			if typeName == REDSHIFT_NUMERIC && options.decimalAsString {
+				ret[i] = *curr.StringValue
+				continue
+			}
 			bitSize := 64
 			if typeName == REDSHIFT_FLOAT4 {
 				bitSize = 32
@@ -237,8 +295,15 @@ func convertRow(columns []*redshiftdataapiservice.ColumnMetadata, data []*redshi
 			} else {
 				ret[i] = *curr.DoubleValue
 			}
-		case REDSHIFT_BOOL:
-			// don't know why boolean values are not passed as curr.BooleanValue
+		case REDSHIFT_BOOL, REDSHIFT_BOOLEAN:
+			// Booleans are returned as booleanValue, a string value is still parsed if it's set instead
+			if curr.BooleanValue != nil {
+				ret[i] = *curr.BooleanValue
+				continue
+			}
+			if curr.StringValue == nil {
+				return fmt.Errorf("missing value of boolean column %s", aws.StringValue(col.Name))
+			}
 			boolValue, err := strconv.ParseBool(*curr.StringValue)
 			if err != nil {
 				return err
@@ -258,6 +323,9 @@ func convertRow(columns []*redshiftdataapiservice.ColumnMetadata, data []*redshi
 			REDSHIFT_SUPER,
 			REDSHIFT_NAME:
 			ret[i] = *curr.StringValue
+		case REDSHIFT_VARBYTE, REDSHIFT_VARBINARY, REDSHIFT_BINARY_VARYING:
+			// Binary values are in the blob value, they are encoded so they can be displayed
+			ret[i] = base64.StdEncoding.EncodeToString(curr.BlobValue)
 		// Time formats from
 		// https://docs.aws.amazon.com/redshift/latest/dg/r_Datetime_types.html
 		case REDSHIFT_DATE:
@@ -267,13 +335,17 @@ func convertRow(columns []*redshiftdataapiservice.ColumnMetadata, data []*redshi
 			}
 			ret[i] = t
 		case REDSHIFT_TIMESTAMP:
-			t, err := time.Parse("2006-01-02 15:04:05", *curr.StringValue)
+			location := options.location
+			if location == nil {
+				location = time.UTC
+			}
+			t, err := time.ParseInLocation("2006-01-02 15:04:05", *curr.StringValue, location)
 			if err != nil {
 				return err
 			}
 			ret[i] = t
 		case REDSHIFT_TIMESTAMP_WITH_TIME_ZONE:
-			t, err := time.Parse("2006-01-02 15:04:05+00", *curr.StringValue)
+			t, err := parseTimestampTZ(*curr.StringValue)
 			if err != nil {
 				return err
 			}
@@ -291,3 +363,16 @@ func convertRow(columns []*redshiftdataapiservice.ColumnMetadata, data []*redshi
 	}
 	return nil
 }
+
+// parseTimestampTZ parses TIMESTAMPTZ values, whose offset is given in hours (+02) or hours and minutes (+05:30).
+// The values are returned in UTC.
+func parseTimestampTZ(value string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02 15:04:05-07", value)
+	if err != nil {
+		var errMinutes error
+		if t, errMinutes = time.Parse("2006-01-02 15:04:05-07:00", value); errMinutes != nil {
+			return time.Time{}, err
+		}
+	}
+	return t.UTC(), nil
+}
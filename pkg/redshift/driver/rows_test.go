@@ -1,13 +1,17 @@
 package driver
 
 import (
+	"context"
 	"database/sql/driver"
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/grafana/redshift-datasource/pkg/redshift/api"
 	redshiftservicemock "github.com/grafana/redshift-datasource/pkg/redshift/driver/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,7 +20,7 @@ import (
 func TestOnePageSuccess(t *testing.T) {
 	redshiftServiceMock := &redshiftservicemock.RedshiftService{}
 	redshiftServiceMock.CalledTimesCountDown = 1
-	rows, rowErr := newRows(redshiftServiceMock, redshiftservicemock.SinglePageResponseQueryId)
+	rows, rowErr := newRows(context.Background(), &api.API{DataClient: redshiftServiceMock}, redshiftservicemock.SinglePageResponseQueryId, rowOptions{})
 	require.NoError(t, rowErr)
 	cnt := 0
 	for {
@@ -35,10 +39,17 @@ func TestOnePageSuccess(t *testing.T) {
 	require.Equal(t, 2, cnt)
 }
 
+func TestEmptyRows(t *testing.T) {
+	rows := newEmptyRows()
+	require.Empty(t, rows.Columns())
+	require.ErrorIs(t, rows.Next([]driver.Value{}), io.EOF)
+	require.NoError(t, rows.Close())
+}
+
 func TestMultiPageSuccess(t *testing.T) {
 	redshiftServiceMock := &redshiftservicemock.RedshiftService{}
 	redshiftServiceMock.CalledTimesCountDown = 5
-	rows, rowErr := newRows(redshiftServiceMock, redshiftservicemock.MultiPageResponseQueryId)
+	rows, rowErr := newRows(context.Background(), &api.API{DataClient: redshiftServiceMock}, redshiftservicemock.MultiPageResponseQueryId, rowOptions{})
 	require.NoError(t, rowErr)
 	cnt := 0
 	for {
@@ -58,6 +69,41 @@ func TestMultiPageSuccess(t *testing.T) {
 	require.Equal(t, 5, redshiftServiceMock.CalledTimesCounter)
 }
 
+func TestPageRetried(t *testing.T) {
+	redshiftServiceMock := &redshiftservicemock.RedshiftService{}
+	redshiftServiceMock.CalledTimesCountDown = 2
+	rows, err := newRows(context.Background(), &api.API{DataClient: redshiftServiceMock}, redshiftservicemock.MultiPageResponseQueryId, rowOptions{})
+	require.NoError(t, err)
+
+	// The second page fails transiently once, it's fetched again with the same token
+	redshiftServiceMock.ResultErrors = []error{awserr.New(redshiftdataapiservice.ErrCodeInternalServerException, "boom", nil)}
+	cnt := 0
+	for {
+		var col1, col2 string
+		err := rows.Next([]driver.Value{&col1, &col2})
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+		cnt++
+	}
+	require.Equal(t, 4, cnt)
+	require.Empty(t, redshiftServiceMock.ResultErrors)
+
+	t.Run("the errors are mapped by the API", func(t *testing.T) {
+		redshiftServiceMock := &redshiftservicemock.RedshiftService{
+			ResultErrors: []error{awserr.New(redshiftdataapiservice.ErrCodeValidationException, "boom", nil)},
+		}
+		_, err := newRows(context.Background(), &api.API{DataClient: redshiftServiceMock}, redshiftservicemock.SinglePageResponseQueryId, rowOptions{})
+		require.ErrorIs(t, err, api.ResultError)
+		require.NotErrorIs(t, err, api.ResultTooLargeError)
+
+		redshiftServiceMock.ResultErrors = []error{awserr.New(redshiftdataapiservice.ErrCodeValidationException, "Query result size exceeds the limit of 100 MB", nil)}
+		_, err = newRows(context.Background(), &api.API{DataClient: redshiftServiceMock}, redshiftservicemock.SinglePageResponseQueryId, rowOptions{})
+		require.ErrorIs(t, err, api.ResultTooLargeError)
+	})
+}
+
 func Test_convertRow(t *testing.T) {
 
 	tests := []struct {
@@ -356,6 +402,17 @@ func Test_convertRow(t *testing.T) {
 			expectedType:  "time.Time",
 			expectedValue: `2021-07-15 14:00:00 +0000 UTC`,
 		},
+		{
+			name: "null super",
+			metadata: &redshiftdataapiservice.ColumnMetadata{
+				TypeName: aws.String(REDSHIFT_SUPER),
+			},
+			data: &redshiftdataapiservice.Field{
+				IsNull: aws.Bool(true),
+			},
+			expectedType:  "<nil>",
+			expectedValue: "<nil>",
+		},
 		{
 			name:     "null",
 			metadata: &redshiftdataapiservice.ColumnMetadata{},
@@ -374,6 +431,7 @@ func Test_convertRow(t *testing.T) {
 				[]*redshiftdataapiservice.ColumnMetadata{tt.metadata},
 				[]*redshiftdataapiservice.Field{tt.data},
 				res,
+				rowOptions{},
 			)
 			require.NoError(t, err)
 			assert.Equal(t, tt.expectedType, fmt.Sprintf("%T", res[0]))
@@ -394,7 +452,7 @@ func Test_convertRow(t *testing.T) {
 			{IsNull: aws.Bool(true)},
 		}
 
-		err := convertRow(metadata, data, res)
+		err := convertRow(metadata, data, res, rowOptions{})
 		require.NoError(t, err)
 
 		expectedValue := []driver.Value{int32(3), nil}
@@ -406,6 +464,184 @@ func Test_convertRow(t *testing.T) {
 			[]*redshiftdataapiservice.ColumnMetadata{{}},
 			[]*redshiftdataapiservice.Field{{}},
 			[]driver.Value{},
+			rowOptions{},
 		), "error in convertRow: col.TypeName is nil")
 	})
 }
+
+func TestColumnTypeDatabaseTypeName(t *testing.T) {
+	rows := &Rows{result: &redshiftdataapiservice.GetStatementResultOutput{
+		ColumnMetadata: []*redshiftdataapiservice.ColumnMetadata{
+			{TypeName: aws.String("super")},
+			{TypeName: aws.String("varchar")},
+		},
+	}}
+	assert.Equal(t, "SUPER", rows.ColumnTypeDatabaseTypeName(0))
+	assert.Equal(t, "VARCHAR", rows.ColumnTypeDatabaseTypeName(1))
+}
+
+func Test_convertRowDecimal(t *testing.T) {
+	metadata := []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("price"), TypeName: aws.String(REDSHIFT_NUMERIC), Precision: aws.Int64(38), Scale: aws.Int64(2)},
+	}
+	// 20 significant digits can't be represented by a float64
+	data := []*redshiftdataapiservice.Field{{StringValue: aws.String("123456789012345678.91")}}
+
+	res := make([]driver.Value, 1)
+	require.NoError(t, convertRow(metadata, data, res, rowOptions{}))
+	assert.NotEqual(t, "123456789012345678.91", fmt.Sprintf("%f", res[0]))
+
+	require.NoError(t, convertRow(metadata, data, res, rowOptions{decimalAsString: true}))
+	assert.Equal(t, "123456789012345678.91", res[0])
+
+	rows := &Rows{
+		result:  &redshiftdataapiservice.GetStatementResultOutput{ColumnMetadata: metadata},
+		options: rowOptions{decimalAsString: true},
+	}
+	assert.Equal(t, "string", rows.ColumnTypeScanType(0).String())
+}
+
+func Test_convertRowTimestamps(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		typeName string
+		value    string
+		options  rowOptions
+		expected time.Time
+	}{
+		{"naive timestamp in UTC", REDSHIFT_TIMESTAMP, "2021-07-15 14:00:00", rowOptions{}, time.Date(2021, 7, 15, 14, 0, 0, 0, time.UTC)},
+		{"naive timestamp in a configured zone", REDSHIFT_TIMESTAMP, "2021-07-15 14:00:00", rowOptions{location: newYork}, time.Date(2021, 7, 15, 18, 0, 0, 0, time.UTC)},
+		{"timestamptz with an hour offset", REDSHIFT_TIMESTAMP_WITH_TIME_ZONE, "2021-07-15 14:00:00+02", rowOptions{}, time.Date(2021, 7, 15, 12, 0, 0, 0, time.UTC)},
+		{"timestamptz with a minute offset", REDSHIFT_TIMESTAMP_WITH_TIME_ZONE, "2021-07-15 14:00:00.5+05:30", rowOptions{}, time.Date(2021, 7, 15, 8, 30, 0, 5e8, time.UTC)},
+		{"timestamptz ignores the configured zone", REDSHIFT_TIMESTAMP_WITH_TIME_ZONE, "2021-07-15 14:00:00+00", rowOptions{location: newYork}, time.Date(2021, 7, 15, 14, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := make([]driver.Value, 1)
+			err := convertRow(
+				[]*redshiftdataapiservice.ColumnMetadata{{TypeName: aws.String(tt.typeName)}},
+				[]*redshiftdataapiservice.Field{{StringValue: aws.String(tt.value)}},
+				res,
+				tt.options,
+			)
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(res[0].(time.Time)), "expected %v, got %v", tt.expected, res[0])
+		})
+	}
+
+	t.Run("invalid timestamptz", func(t *testing.T) {
+		_, err := parseTimestampTZ("2021-07-15")
+		assert.Error(t, err)
+	})
+}
+
+func Test_convertRowVarbyte(t *testing.T) {
+	metadata := []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("payload"), TypeName: aws.String("varbyte")},
+		{Name: aws.String("empty"), TypeName: aws.String("varbyte")},
+		{Name: aws.String("missing"), TypeName: aws.String("varbyte")},
+	}
+	data := []*redshiftdataapiservice.Field{
+		{BlobValue: []byte("hello")},
+		{BlobValue: []byte{}},
+		{IsNull: aws.Bool(true)},
+	}
+
+	res := make([]driver.Value, 3)
+	require.NoError(t, convertRow(metadata, data, res, rowOptions{}))
+	assert.Equal(t, []driver.Value{"aGVsbG8=", "", nil}, res)
+
+	rows := &Rows{result: &redshiftdataapiservice.GetStatementResultOutput{ColumnMetadata: metadata}}
+	assert.Equal(t, "string", rows.ColumnTypeScanType(0).String())
+	assert.Equal(t, "VARBYTE", rows.ColumnTypeDatabaseTypeName(0))
+}
+
+func Test_convertRowBoolean(t *testing.T) {
+	metadata := []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("a"), TypeName: aws.String("bool")},
+		{Name: aws.String("b"), TypeName: aws.String("bool")},
+		{Name: aws.String("c"), TypeName: aws.String("boolean")},
+	}
+	tests := []struct {
+		name     string
+		data     []*redshiftdataapiservice.Field
+		expected []driver.Value
+	}{
+		{
+			"boolean values",
+			[]*redshiftdataapiservice.Field{{BooleanValue: aws.Bool(true)}, {BooleanValue: aws.Bool(false)}, {BooleanValue: aws.Bool(true)}},
+			[]driver.Value{true, false, true},
+		},
+		{
+			"null values",
+			[]*redshiftdataapiservice.Field{{IsNull: aws.Bool(true)}, {BooleanValue: aws.Bool(false)}, {IsNull: aws.Bool(true)}},
+			[]driver.Value{nil, false, nil},
+		},
+		{
+			"string values",
+			[]*redshiftdataapiservice.Field{{StringValue: aws.String("true")}, {StringValue: aws.String("f")}, {IsNull: aws.Bool(true)}},
+			[]driver.Value{true, false, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := make([]driver.Value, 3)
+			require.NoError(t, convertRow(metadata, tt.data, res, rowOptions{}))
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+
+	t.Run("error returned for a missing value", func(t *testing.T) {
+		err := convertRow(metadata[:1], []*redshiftdataapiservice.Field{{}}, make([]driver.Value, 1), rowOptions{})
+		assert.EqualError(t, err, "missing value of boolean column a")
+	})
+
+	rows := &Rows{result: &redshiftdataapiservice.GetStatementResultOutput{ColumnMetadata: metadata}}
+	assert.Equal(t, "bool", rows.ColumnTypeScanType(2).String())
+	assert.Equal(t, "BOOLEAN", rows.ColumnTypeDatabaseTypeName(2))
+}
+
+func Test_convertRowBigint(t *testing.T) {
+	// 2^53 + 1 is rounded to 2^53 as a float64
+	const bigint int64 = 9007199254740993
+	metadata := []*redshiftdataapiservice.ColumnMetadata{
+		{Name: aws.String("a"), TypeName: aws.String("int8")},
+		{Name: aws.String("b"), TypeName: aws.String("int8")},
+		{Name: aws.String("c"), TypeName: aws.String("int8")},
+	}
+	res := make([]driver.Value, 3)
+	require.NoError(t, convertRow(metadata, []*redshiftdataapiservice.Field{
+		{LongValue: aws.Int64(bigint)},
+		{StringValue: aws.String("9007199254740993")},
+		{IsNull: aws.Bool(true)},
+	}, res, rowOptions{}))
+	assert.Equal(t, []driver.Value{bigint, bigint, nil}, res)
+	assert.NotEqual(t, bigint, int64(float64(bigint)))
+
+	t.Run("error returned for a missing value", func(t *testing.T) {
+		err := convertRow(metadata[:1], []*redshiftdataapiservice.Field{{}}, make([]driver.Value, 1), rowOptions{})
+		assert.EqualError(t, err, "invalid value of integer column a: integer field without a value")
+	})
+}
+
+func TestMaxRows(t *testing.T) {
+	redshiftServiceMock := &redshiftservicemock.RedshiftService{}
+	redshiftServiceMock.CalledTimesCountDown = 5
+	rows, rowErr := newRows(context.Background(), &api.API{DataClient: redshiftServiceMock}, redshiftservicemock.MultiPageResponseQueryId, rowOptions{maxRows: 3})
+	require.NoError(t, rowErr)
+	cnt := 0
+	for {
+		var col1, col2 string
+		err := rows.Next([]driver.Value{&col1, &col2})
+		if err != nil {
+			require.ErrorIs(t, io.EOF, err)
+			break
+		}
+		cnt++
+	}
+	require.Equal(t, 3, cnt)
+	// The pages after the limit are not fetched
+	require.Equal(t, 2, redshiftServiceMock.CalledTimesCounter)
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 	"github.com/grafana/grafana-aws-sdk/pkg/sql/models"
@@ -13,11 +14,17 @@ import (
 type ManagedSecret struct {
 	Name string `json:"name"`
 	ARN  string `json:"arn"`
+	// Untagged is true for the secrets listed without the tag filter, see ListUntaggedSecrets
+	Untagged bool `json:"untagged,omitempty"`
 }
 
 type RedshiftSecret struct {
 	ClusterIdentifier string `json:"dbClusterIdentifier"`
 	DBUser            string `json:"username"`
+	// Host, Port and Database are optional, they are set in the secrets created for a cluster
+	Host     string      `json:"host,omitempty"`
+	Port     json.Number `json:"port,omitempty"`
+	Database string      `json:"dbname,omitempty"`
 }
 
 type RedshiftEndpoint struct {
@@ -35,10 +42,74 @@ type RedshiftDataSourceSettings struct {
 	awsds.AWSDatasourceSettings
 	Config            backend.DataSourceInstanceSettings
 	ClusterIdentifier string `json:"clusterIdentifier"`
+	WorkgroupName     string `json:"workgroupName"`
 	Database          string `json:"database"`
-	UseManagedSecret  bool   `json:"useManagedSecret"`
-	DBUser            string `json:"dbUser"`
-	ManagedSecret     ManagedSecret
+	// The statements use the managed secret if UseManagedSecret is set, otherwise temporary credentials
+	// of DBUser. DBUser can be set with a managed secret too, for the API ClusterCredentials only.
+	UseManagedSecret bool   `json:"useManagedSecret"`
+	DBUser           string `json:"dbUser"`
+	ManagedSecret    ManagedSecret
+	// DefaultSchema is the schema of the tables when none is selected, public by default
+	DefaultSchema string `json:"defaultSchema"`
+	// SecretTagKey is the tag that secrets need to be listed, RedshiftQueryOwner by default.
+	// SecretTagValue optionally restricts the list to secrets with a tag of that value.
+	SecretTagKey   string `json:"secretTagKey"`
+	SecretTagValue string `json:"secretTagValue"`
+	// SecretsRegion is the region of the managed secrets when it's not the region of the cluster
+	SecretsRegion string `json:"secretsRegion"`
+	// QuerySecretARN is the managed secret chosen by the query arguments, it overrides the credentials of the settings
+	QuerySecretARN string `json:"-"`
+	// ListUntaggedSecrets lists every secret, flagged as untagged, when none has the tag of the secrets to list
+	ListUntaggedSecrets bool `json:"listUntaggedSecrets"`
+	// AllowUnknownRegion skips the check of the regions against the endpoints known by the SDK, e.g. for a new region
+	AllowUnknownRegion bool `json:"allowUnknownRegion"`
+	// DisableManagedSecrets doesn't create the Secrets Manager client, for setups that use a database user only
+	DisableManagedSecrets bool `json:"disableManagedSecrets"`
+	// PollInterval is the initial delay in milliseconds between two polls of the status of a statement,
+	// MaxPollInterval the longest one and PollFactor the growth of the delay. 0 uses the defaults.
+	PollInterval    int     `json:"pollInterval"`
+	MaxPollInterval int     `json:"maxPollInterval"`
+	PollFactor      float64 `json:"pollFactor"`
+	// MaxRetries is the number of times a throttled Data API or Secrets Manager call is retried.
+	// 0 uses the default and a negative value disables the retries.
+	MaxRetries int `json:"maxRetries"`
+	// RetryDelay is the initial delay in milliseconds between retries, 0 uses the default
+	RetryDelay int `json:"retryDelay"`
+	// RetryBudget is the total number of retries of the calls made for a query, 0 uses the default
+	// and a negative value only limits the retries of each call
+	RetryBudget int `json:"retryBudget"`
+	// CacheTTL is the number of seconds schemas, tables and columns are cached.
+	// 0 uses the default and a negative value disables the cache.
+	CacheTTL int `json:"cacheTTL"`
+	// MaxPages is the number of pages a list (e.g. of tables) can have before it's stopped, 0 uses the default
+	MaxPages int `json:"maxPages"`
+	// StrictLists fails the lists with entries without a name instead of skipping them
+	StrictLists bool `json:"strictLists"`
+	// HealthCheckTimeout is the number of seconds the health check query can take, 0 uses the default
+	HealthCheckTimeout int `json:"healthCheckTimeout"`
+	// QueryTimeout is the number of seconds a statement can run before it's canceled, 0 disables it
+	QueryTimeout int `json:"queryTimeout"`
+	// CallTimeout is the number of seconds a single AWS call, e.g. a page of ListTables, may take, 0 means no limit.
+	// It applies on top of the deadline of the request.
+	CallTimeout int `json:"callTimeout"`
+	// AutoResume resumes a paused cluster when a statement is rejected because of it and runs the statement
+	// once the cluster is available. It needs the redshift:ResumeCluster and redshift:DescribeClusters permissions.
+	AutoResume bool `json:"autoResume"`
+	// AutoResumeTimeout is the number of seconds to wait for the cluster to be resumed, 0 uses the default
+	AutoResumeTimeout int `json:"autoResumeTimeout"`
+	// MaxRows is the number of rows of a query result fetched at most, 0 disables the limit
+	MaxRows int `json:"maxRows"`
+	// LimitQueries adds a LIMIT to the SELECT queries without one when MaxRows is set,
+	// so the cluster stops early as well
+	LimitQueries bool `json:"limitQueries"`
+	// DecimalAsString returns DECIMAL/NUMERIC values as strings instead of floats, which lose precision
+	// for values with more than 15 significant digits
+	DecimalAsString bool `json:"decimalAsString"`
+	// TimestampTimeZone is the IANA time zone of the TIMESTAMP values, which have none. UTC by default.
+	TimestampTimeZone string `json:"timestampTimeZone"`
+	// WithEvent makes the Data API send an EventBridge event when a statement finishes.
+	// Queries are still polled for their status.
+	WithEvent bool `json:"withEvent"`
 }
 
 func New() models.Settings {
@@ -57,11 +128,71 @@ func (s *RedshiftDataSourceSettings) Load(config backend.DataSourceInstanceSetti
 
 	s.Config = config
 
+	return s.ApplyEnv(os.LookupEnv)
+}
+
+// The environment variables overriding the settings of every data source, e.g. in a container
+const (
+	EnvRegion            = "REDSHIFT_REGION"
+	EnvClusterIdentifier = "REDSHIFT_CLUSTER_IDENTIFIER"
+	EnvWorkgroupName     = "REDSHIFT_WORKGROUP_NAME"
+	EnvDatabase          = "REDSHIFT_DATABASE"
+	EnvDBUser            = "REDSHIFT_DB_USER"
+	EnvSecretARN         = "REDSHIFT_SECRET_ARN"
+	EnvSecretsRegion     = "REDSHIFT_SECRETS_REGION"
+)
+
+// ApplyEnv overrides the settings with the environment variables found by lookup, they take precedence
+// over the JSON data. The cluster identifier and the workgroup name replace each other but can't be both
+// set, and a secret ARN enables the managed secret. The settings are unchanged if no variable is set.
+func (s *RedshiftDataSourceSettings) ApplyEnv(lookup func(string) (string, bool)) error {
+	get := func(name string) (string, bool) {
+		value, ok := lookup(name)
+		return value, ok && value != ""
+	}
+	_, hasCluster := get(EnvClusterIdentifier)
+	_, hasWorkgroup := get(EnvWorkgroupName)
+	if hasCluster && hasWorkgroup {
+		return fmt.Errorf("%s and %s are mutually exclusive, only one of them can be set", EnvClusterIdentifier, EnvWorkgroupName)
+	}
+	if v, ok := get(EnvRegion); ok {
+		s.DefaultRegion = v
+	}
+	if v, ok := get(EnvClusterIdentifier); ok {
+		s.ClusterIdentifier, s.WorkgroupName = v, ""
+	}
+	if v, ok := get(EnvWorkgroupName); ok {
+		s.WorkgroupName, s.ClusterIdentifier = v, ""
+	}
+	if v, ok := get(EnvDatabase); ok {
+		s.Database = v
+	}
+	if v, ok := get(EnvDBUser); ok {
+		s.DBUser = v
+	}
+	if v, ok := get(EnvSecretARN); ok {
+		s.UseManagedSecret = true
+		s.ManagedSecret = ManagedSecret{ARN: v}
+	}
+	if v, ok := get(EnvSecretsRegion); ok {
+		s.SecretsRegion = v
+	}
 	return nil
 }
 
+// ApplySecret uses the cluster and database of the secret when they are not configured
+func (s *RedshiftDataSourceSettings) ApplySecret(secret *RedshiftSecret) {
+	if s.ClusterIdentifier == "" && s.WorkgroupName == "" {
+		s.ClusterIdentifier = secret.ClusterIdentifier
+	}
+	if s.Database == "" {
+		s.Database = secret.Database
+	}
+}
+
 func (s *RedshiftDataSourceSettings) Apply(args sqlds.Options) {
-	region, database := args["region"], args["database"]
+	// The secret of the query has its own argument, secretARN is the secret read by the Secret resource
+	region, database, secretARN := args["region"], args["database"], args["querySecretARN"]
 	if region != "" {
 		if region == models.DefaultKey {
 			s.Region = s.DefaultRegion
@@ -73,4 +204,8 @@ func (s *RedshiftDataSourceSettings) Apply(args sqlds.Options) {
 	if database != "" && database != models.DefaultKey {
 		s.Database = database
 	}
+
+	if secretARN != "" && secretARN != models.DefaultKey {
+		s.QuerySecretARN = secretARN
+	}
 }
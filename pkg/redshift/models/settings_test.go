@@ -0,0 +1,108 @@
+package models
+
+import (
+	"os"
+	"testing"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/sql/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad_AssumeRole(t *testing.T) {
+	s := &RedshiftDataSourceSettings{}
+	err := s.Load(backend.DataSourceInstanceSettings{
+		JSONData: []byte(`{"assumeRoleARN":"arn:aws:iam::123456789012:role/redshift","externalId":"team-a","clusterIdentifier":"cluster"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/redshift", s.AssumeRoleARN)
+	assert.Equal(t, "team-a", s.ExternalID)
+	assert.Equal(t, "cluster", s.ClusterIdentifier)
+}
+
+func TestApplySecret(t *testing.T) {
+	secret := &RedshiftSecret{ClusterIdentifier: "secret-cluster", Database: "secret-db"}
+
+	s := &RedshiftDataSourceSettings{}
+	s.ApplySecret(secret)
+	assert.Equal(t, "secret-cluster", s.ClusterIdentifier)
+	assert.Equal(t, "secret-db", s.Database)
+
+	s = &RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"}
+	s.ApplySecret(secret)
+	assert.Equal(t, "cluster", s.ClusterIdentifier)
+	assert.Equal(t, "db", s.Database)
+
+	s = &RedshiftDataSourceSettings{WorkgroupName: "workgroup"}
+	s.ApplySecret(&RedshiftSecret{ClusterIdentifier: "secret-cluster"})
+	assert.Equal(t, "", s.ClusterIdentifier)
+	assert.Equal(t, "", s.Database)
+}
+
+func TestApplySecretARN(t *testing.T) {
+	s := &RedshiftDataSourceSettings{UseManagedSecret: true, ManagedSecret: ManagedSecret{ARN: "configured"}}
+	s.Apply(map[string]string{"querySecretARN": models.DefaultKey})
+	assert.Equal(t, "", s.QuerySecretARN)
+
+	// The argument of the Secret resource isn't the secret of the query
+	s.Apply(map[string]string{"secretARN": "resource"})
+	assert.Equal(t, "", s.QuerySecretARN)
+
+	s.Apply(map[string]string{"querySecretARN": "query"})
+	assert.Equal(t, "query", s.QuerySecretARN)
+	assert.Equal(t, "configured", s.ManagedSecret.ARN)
+}
+
+func TestApplyEnv(t *testing.T) {
+	lookup := func(env map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		}
+	}
+
+	t.Run("keeps the settings without variables", func(t *testing.T) {
+		s := &RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"}
+		assert.NoError(t, s.ApplyEnv(lookup(map[string]string{EnvDatabase: ""})))
+		assert.Equal(t, &RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"}, s)
+	})
+
+	t.Run("overrides the settings", func(t *testing.T) {
+		s := &RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db", DBUser: "user"}
+		assert.NoError(t, s.ApplyEnv(lookup(map[string]string{
+			EnvRegion:        "eu-west-1",
+			EnvWorkgroupName: "workgroup",
+			EnvDatabase:      "other",
+			EnvSecretARN:     "arn:secret",
+			EnvSecretsRegion: "us-east-1",
+		})))
+		assert.Equal(t, "eu-west-1", s.DefaultRegion)
+		assert.Equal(t, "", s.ClusterIdentifier)
+		assert.Equal(t, "workgroup", s.WorkgroupName)
+		assert.Equal(t, "other", s.Database)
+		assert.Equal(t, "user", s.DBUser)
+		assert.True(t, s.UseManagedSecret)
+		assert.Equal(t, "arn:secret", s.ManagedSecret.ARN)
+		assert.Equal(t, "us-east-1", s.SecretsRegion)
+	})
+
+	t.Run("rejects both a cluster and a workgroup", func(t *testing.T) {
+		s := &RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"}
+		err := s.ApplyEnv(lookup(map[string]string{
+			EnvClusterIdentifier: "env-cluster",
+			EnvWorkgroupName:     "env-workgroup",
+		}))
+		assert.EqualError(t, err, "REDSHIFT_CLUSTER_IDENTIFIER and REDSHIFT_WORKGROUP_NAME are mutually exclusive, only one of them can be set")
+		assert.Equal(t, &RedshiftDataSourceSettings{ClusterIdentifier: "cluster", Database: "db"}, s)
+	})
+
+	t.Run("is applied when loading the settings", func(t *testing.T) {
+		os.Setenv(EnvClusterIdentifier, "env-cluster")
+		defer os.Unsetenv(EnvClusterIdentifier)
+		s := &RedshiftDataSourceSettings{}
+		err := s.Load(backend.DataSourceInstanceSettings{JSONData: []byte(`{"clusterIdentifier":"cluster","database":"db"}`)})
+		assert.NoError(t, err)
+		assert.Equal(t, "env-cluster", s.ClusterIdentifier)
+		assert.Equal(t, "db", s.Database)
+	})
+}
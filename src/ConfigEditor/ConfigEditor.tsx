@@ -60,7 +60,11 @@ export function ConfigEditor(props: Props) {
   // Secrets
   const fetchSecrets = async () => {
     const res: RedshiftManagedSecret[] = await getBackendSrv().get(resourcesURL + '/secrets');
-    return res.map((r) => ({ label: r.name, value: r.arn, description: r.arn }));
+    return res.map((r) => ({
+      label: r.untagged ? `${r.name} (untagged)` : r.name,
+      value: r.arn,
+      description: r.arn,
+    }));
   };
   const { arn } = props.options.jsonData.managedSecret || {};
   const fetchSecret = async (arn: string) => {
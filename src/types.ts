@@ -33,6 +33,7 @@ export interface RedshiftQuery extends SQLQuery {
 export interface RedshiftManagedSecret {
   name: string;
   arn: string;
+  untagged?: boolean;
 }
 
 export const defaultKey = '__default';
@@ -49,6 +50,7 @@ export const defaultQuery: Partial<RedshiftQuery> = {
 export interface RedshiftDataSourceOptions extends AwsAuthDataSourceJsonData {
   useManagedSecret?: boolean;
   clusterIdentifier?: string;
+  workgroupName?: string;
   database?: string;
   dbUser?: string;
   managedSecret?: {